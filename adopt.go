@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modinfo"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	A_FLAG_ENABLED = "enabled"
+	A_FLAG_VERIFY  = "verify"
+)
+
+// an archive without a mod list entry
+type adoptee struct {
+	archive *modlist.Archive
+	info    *modinfo.Info
+}
+
+// add archives that are not in the mod list to it
+func adopt(flags *ModtorioFlags, options []string) error {
+	var enabled, verify, dryRun bool
+
+	adoptFlags := flag.NewFlagSet("Adopt flags", flag.ContinueOnError)
+
+	adoptFlags.BoolVar(&enabled, A_FLAG_ENABLED, false, "Enable the adopted mods")
+	adoptFlags.BoolVar(&verify, A_FLAG_VERIFY, false, "Check the archives are published releases on the mod portal")
+	adoptFlags.BoolVar(&dryRun, E_FLAG_DRY_RUN, false, "Show the archives that would be adopted without adopting them")
+	e := adoptFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	archives, _, e := modlist.ScanArchives(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	// newest archive of each mod without an entry, by canonical name
+	adoptees := map[string]*adoptee{}
+	var names []string
+
+	for _, archive := range archives {
+		info, e := modinfo.ReadZip(filepath.Join(flags.dir, archive.File))
+
+		if e != nil {
+			fmt.Printf("Skipping %s: %v\n", archive.File, e)
+			continue
+		}
+
+		if list.Get(info.Name) != nil || modlist.IsBuiltin(info.Name, flags.factorio) {
+			// already in the list
+			continue
+		}
+
+		if info.Name != archive.Mod || info.Version != archive.Version {
+			fmt.Printf("Warning: %s contains %s %s\n", archive.File, info.Name, info.Version)
+		}
+
+		a, ok := adoptees[info.Name]
+
+		if !ok {
+			names = append(names, info.Name)
+		} else if semverOf(info).Cmp(semverOf(a.info)) <= 0 {
+			// an older version of a mod being adopted
+			continue
+		}
+
+		adoptees[info.Name] = &adoptee{archive, info}
+	}
+
+	if len(names) == 0 {
+		fmt.Println("Nothing to adopt")
+
+		return nil
+	}
+
+	if verify {
+		e = verifyAdoptees(adoptees, names, flags.dir)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	for _, name := range names {
+		a := adoptees[name]
+		fmt.Printf("Adopting %s %s (%s)\n", a.info.Name, a.info.Version, a.archive.File)
+		list.Mods = append(list.Mods, &modlist.Mod{Name: name, Enabled: enabled})
+	}
+
+	if dryRun {
+		fmt.Println("Dry run: no changes made")
+
+		return nil
+	}
+
+	return list.Write(flags.dir)
+}
+
+// check the adoptees are published releases on the mod portal
+func verifyAdoptees(adoptees map[string]*adoptee, names []string, dir string) error {
+	results, e := api.GetAll(names...)
+
+	if e != nil {
+		return e
+	}
+
+	for _, name := range names {
+		a := adoptees[name]
+		release := findRelease(results, name, a.info.Version)
+
+		if release == nil {
+			fmt.Printf("Warning: %s %s is not a release on the mod portal\n", name, a.info.Version)
+			continue
+		}
+
+		sum, e := common.Sha1File(filepath.Join(dir, a.archive.File))
+
+		if e != nil {
+			return e
+		}
+
+		if release.Sha1 != "" && sum != release.Sha1 {
+			fmt.Printf("Warning: %s does not match the published release of %s %s\n", a.archive.File, name, a.info.Version)
+		} else {
+			fmt.Printf("%s %s matches the published release\n", name, a.info.Version)
+		}
+	}
+
+	return nil
+}
+
+// parse the version of a mod, treating unparseable versions as 0.0.0
+func semverOf(info *modinfo.Info) *common.Semver {
+	semver, e := common.NewSemver(info.Version)
+
+	if e != nil {
+		return &common.Semver{}
+	}
+
+	return semver
+}
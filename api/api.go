@@ -2,8 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+
+	"github.com/blacksfk/modtorio/common"
 )
 
 // auxiliary function to check for request errors
@@ -34,3 +37,21 @@ func handleResponse(res *http.Response) ([]byte, error) {
 
 	return body, nil
 }
+
+// download a file from any URL to path. the file is only moved into
+// place once it has been written completely
+func DownloadURL(url, path string) error {
+	res, e := get(url)
+
+	if e != nil {
+		return e
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s: %s", url, res.Status)
+	}
+
+	return common.WriteAtomic(path, res.Body, MODE)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/credentials"
+)
+
+const (
+	URL_LATEST   = "https://factorio.com/api/latest-releases"
+	URL_GAME     = "https://www.factorio.com/get-download/%s/headless/linux64"
+	URL_SHA256   = "https://factorio.com/download/sha256sums/"
+	STABLE       = "stable"
+	EXPERIMENTAL = "experimental"
+)
+
+// latest game versions of each build, returned from factorio.com/api/latest-releases
+type Builds struct {
+	Alpha, Demo, Expansion, Headless string
+}
+
+type LatestReleases struct {
+	Stable, Experimental Builds
+}
+
+// check if a string is a release channel rather than a version
+func IsChannel(s string) bool {
+	return s == STABLE || s == EXPERIMENTAL
+}
+
+// Get the latest game versions of every build.
+func GetLatestReleases() (*LatestReleases, error) {
+	res, e := get(URL_LATEST)
+
+	if e != nil {
+		return nil, e
+	}
+
+	body, e := handleResponse(res)
+
+	if e != nil {
+		return nil, e
+	}
+
+	latest := &LatestReleases{}
+	e = json.Unmarshal(body, latest)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return latest, nil
+}
+
+// Get the latest headless server version on a release channel. The latest
+// releases are cached at path (if not empty), and the cache is used when
+// factorio.com cannot be reached, in which case cached is true.
+func LatestVersion(channel, path string) (version string, cached bool, e error) {
+	latest, e := GetLatestReleases()
+
+	if e == nil && path != "" {
+		// best effort: the version is known either way
+		if bytes, e := json.Marshal(latest); e == nil {
+			common.WriteFileAtomic(path, bytes, MODE)
+		}
+	} else if e != nil {
+		bytes, ce := os.ReadFile(path)
+
+		if ce != nil {
+			return "", false, fmt.Errorf("Cannot get the latest %s release: %v", channel, e)
+		}
+
+		latest = &LatestReleases{}
+		e = json.Unmarshal(bytes, latest)
+
+		if e != nil {
+			return "", false, fmt.Errorf("Invalid cache %s: %v", path, e)
+		}
+
+		cached = true
+	}
+
+	builds := latest.Stable
+
+	if channel == EXPERIMENTAL {
+		builds = latest.Experimental
+	}
+
+	if builds.Headless == "" {
+		return "", false, fmt.Errorf("No %s headless release found", channel)
+	}
+
+	return builds.Headless, cached, nil
+}
+
+// Download the headless server tarball of a game version to path and check
+// it against the published SHA256 checksums. Downloads require a
+// factorio.com login.
+func DownloadGame(version, path string, creds *credentials.Credentials) error {
+	sums, e := gameChecksums()
+
+	if e != nil {
+		return e
+	}
+
+	expected := ""
+
+	for name, sum := range sums {
+		if strings.Contains(name, "headless") && strings.Contains(name, "_"+version+".tar") {
+			expected = sum
+		}
+	}
+
+	if expected == "" {
+		return fmt.Errorf("No published checksum for the %s headless server", version)
+	}
+
+	query := url.Values{}
+	query.Set("username", creds.Username)
+	query.Set("token", creds.Token)
+	res, e := get(fmt.Sprintf(URL_GAME, version) + "?" + query.Encode())
+
+	if e != nil {
+		return e
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("Downloading factorio %s: %s", version, res.Status)
+	}
+
+	hash := sha256.New()
+	e = common.WriteAtomic(path, io.TeeReader(res.Body, hash), MODE)
+
+	if e != nil {
+		return e
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != expected {
+		os.Remove(path)
+
+		return fmt.Errorf("Checksum mismatch for factorio %s: expected %s, got %s", version, expected, sum)
+	}
+
+	return nil
+}
+
+// get the published SHA256 checksums of every game download, by file name
+func gameChecksums() (map[string]string, error) {
+	res, e := get(URL_SHA256)
+
+	if e != nil {
+		return nil, e
+	}
+
+	body, e := handleResponse(res)
+
+	if e != nil {
+		return nil, e
+	}
+
+	// lines of "<sum>  <file name>"
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(string(body)))
+
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			sums[fields[1]] = fields[0]
+		}
+	}
+
+	return sums, scanner.Err()
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	USER_AGENT = "modtorio"
+)
+
+// HTTP client used for every request to factorio.com. Replace or configure
+// it (eg. with SetTimeout) before making any requests.
+var Client = &http.Client{}
+
+// Limit how long a request may take, including reading the response body.
+// Zero means no limit.
+func SetTimeout(timeout time.Duration) {
+	Client.Timeout = timeout
+}
+
+// send a GET request with the client
+func get(url string) (*http.Response, error) {
+	req, e := http.NewRequest(http.MethodGet, url, nil)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return do(req)
+}
+
+// send a form as a POST request with the client
+func postForm(url string, data url.Values) (*http.Response, error) {
+	req, e := http.NewRequest(http.MethodPost, url, strings.NewReader(data.Encode()))
+
+	if e != nil {
+		return nil, e
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return do(req)
+}
+
+func do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", USER_AGENT)
+
+	return Client.Do(req)
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"net/http"
 	"net/url"
 )
 
@@ -18,7 +17,7 @@ func Login(username, password string) (string, error) {
 	data.Set("password", password)
 
 	// send the request
-	res, e := http.PostForm(URL_LOGIN, data)
+	res, e := postForm(URL_LOGIN, data)
 
 	if e != nil {
 		return "", e
@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"net/http"
 	"strings"
 )
 
@@ -39,7 +38,7 @@ func GetAll(names ...string) ([]*Result, error) {
 
 	// get all mods in one shot by requesting a "page" with all of the mods
 	// i.e. page_size=max
-	res, e := http.Get(url.String())
+	res, e := get(url.String())
 
 	if e != nil {
 		return nil, e
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/blacksfk/modtorio/common"
@@ -33,14 +37,20 @@ type Pagination struct {
 
 // mod data
 type Result struct {
-	Downloads_count              uint
-	Name, Owner, Summary         string
-	Title, Changelog, Created_at string
-	Description, Github_path     string
-	Category, Homepage           string
-	Latest_release               *Release
-	Releases                     []*Release
-	Tag                          []*Tag
+	Downloads_count uint       `json:"downloads_count"`
+	Name            string     `json:"name"`
+	Owner           string     `json:"owner"`
+	Summary         string     `json:"summary"`
+	Title           string     `json:"title"`
+	Changelog       string     `json:"changelog"`
+	Created_at      string     `json:"created_at"`
+	Description     string     `json:"description"`
+	Github_path     string     `json:"github_path"`
+	Category        string     `json:"category"`
+	Homepage        string     `json:"homepage"`
+	Latest_release  *Release   `json:"latest_release"`
+	Releases        []*Release `json:"releases"`
+	Tag             []*Tag     `json:"tag"`
 }
 
 // pretty print a mod's information
@@ -50,13 +60,16 @@ func (r *Result) String() string {
 
 // specific release information of a mod
 type Release struct {
-	Download_url, File_name    string
-	Released_at, Version, sha1 string
-	Semver                     *common.Semver
-	Info_json                  struct {
-		Factorio_version string
-		Semver           *common.Semver
-	}
+	Download_url string         `json:"download_url"`
+	File_name    string         `json:"file_name"`
+	Released_at  string         `json:"released_at"`
+	Version      string         `json:"version"`
+	Sha1         string         `json:"sha1"`
+	Semver       *common.Semver `json:"-"`
+	Info_json    struct {
+		Factorio_version string         `json:"factorio_version"`
+		Semver           *common.Semver `json:"-"`
+	} `json:"info_json"`
 }
 
 // compare release version
@@ -79,30 +92,37 @@ func (r *Release) Download(dir string, creds *credentials.Credentials) error {
 	b.WriteString("&token=")
 	b.WriteString(creds.Token)
 
-	res, e := http.Get(b.String())
+	res, e := get(b.String())
 
 	if e != nil {
 		return e
 	}
 
-	body, e := handleResponse(res)
+	if res.StatusCode >= http.StatusBadRequest {
+		// read the error from the body
+		_, e = handleResponse(res)
 
-	if e != nil {
 		return e
 	}
 
-	if dir[len(dir)-1] != '/' {
-		// append a slash
-		dir += "/"
-	}
+	defer res.Body.Close()
 
-	path := dir + r.File_name
-	e = os.WriteFile(path, body, MODE)
+	// stream the archive to disk, hashing it on the way. it is only
+	// moved into place once it has been written completely
+	path := filepath.Join(dir, r.File_name)
+	hash := sha1.New()
+	e = common.WriteAtomic(path, io.TeeReader(res.Body, hash), MODE)
 
 	if e != nil {
 		return e
 	}
 
+	if sum := hex.EncodeToString(hash.Sum(nil)); r.Sha1 != "" && sum != r.Sha1 {
+		os.Remove(path)
+
+		return fmt.Errorf("Checksum mismatch for %s: expected %s, got %s", r.File_name, r.Sha1, sum)
+	}
+
 	return nil
 }
 
@@ -123,8 +143,11 @@ func (r *Release) ParseVersions() error {
 
 // mod tags (refer to array above)
 type Tag struct {
-	Id                             int
-	Name, Title, Description, Type string
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
 }
 
 // JSON API errors
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modinfo"
+	"github.com/blacksfk/modtorio/modlist"
+	"github.com/blacksfk/modtorio/snapshot"
+)
+
+const (
+	C_FLAG_QUARANTINE = "quarantine"
+	QUARANTINE_DIR    = "quarantine"
+)
+
+// a file to be cleaned up and why
+type cleanFile struct {
+	name, reason string
+	size         int64
+}
+
+// remove (or quarantine) archives that are not in the mod list, superseded
+// by a newer version of the same mod, or not named like mods
+func clean(flags *ModtorioFlags, options []string) error {
+	var quarantine, dryRun bool
+
+	cleanFlags := flag.NewFlagSet("Clean flags", flag.ContinueOnError)
+
+	cleanFlags.BoolVar(&quarantine, C_FLAG_QUARANTINE, false, "Move files to the quarantine folder instead of deleting them")
+	cleanFlags.BoolVar(&dryRun, E_FLAG_DRY_RUN, false, "Only report the files that would be cleaned up")
+	e := cleanFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	files, unreadable, e := findCleanFiles(flags.dir, list)
+
+	if e != nil {
+		return e
+	}
+
+	if len(unreadable) > 0 {
+		fmt.Println("Unreadable archives, not cleaned up. Check them with verify and fix them with repair:")
+
+		for _, archive := range unreadable {
+			fmt.Printf("\t%s\n", archive)
+		}
+
+		fmt.Println()
+	}
+
+	if len(files) == 0 {
+		fmt.Println("Nothing to clean up")
+
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+
+	var total int64
+	longest := 0
+
+	for _, file := range files {
+		if info, e := os.Stat(filepath.Join(flags.dir, file.name)); e == nil {
+			file.size = info.Size()
+			total += file.size
+		}
+
+		if l := len(file.name); l > longest {
+			longest = l
+		}
+	}
+
+	for _, file := range files {
+		fmt.Printf("%-*s  %10s  %s\n", longest, file.name, common.FormatSize(file.size), file.reason)
+	}
+
+	fmt.Printf("\n%d files, %s\n", len(files), common.FormatSize(total))
+
+	if dryRun {
+		fmt.Println("Dry run: no changes made")
+
+		return nil
+	}
+
+	if quarantine {
+		fmt.Printf("Files will be moved to %s\n", common.StatePath(flags.dir, QUARANTINE_DIR))
+	}
+
+	ok, e := confirm()
+
+	if e != nil {
+		return e
+	}
+
+	if !ok {
+		return fmt.Errorf("Clean cancelled")
+	}
+
+	dest := ""
+
+	if quarantine {
+		// keep each clean up apart so nothing is overwritten
+		dest, e = common.MkStateDir(flags.dir, QUARANTINE_DIR, time.Now().Format(snapshot.TIME_FORMAT))
+
+		if e != nil {
+			return e
+		}
+	}
+
+	for _, file := range files {
+		path := filepath.Join(flags.dir, file.name)
+
+		if quarantine {
+			e = os.Rename(path, filepath.Join(dest, file.name))
+		} else {
+			e = os.Remove(path)
+		}
+
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// find the files in dir to be cleaned up. archives whose info.json cannot be
+// read may be corrupt or partial downloads, which verify and repair deal with
+// rather than clean, so they are returned separately
+func findCleanFiles(dir string, list *modlist.ModList) ([]*cleanFile, []string, error) {
+	archives, unparseable, e := modlist.ScanArchives(dir)
+
+	if e != nil {
+		return nil, nil, e
+	}
+
+	var files []*cleanFile
+	var unreadable []string
+
+	for _, name := range unparseable {
+		files = append(files, &cleanFile{name: name, reason: "not named <mod>_<version>.zip"})
+	}
+
+	// newest readable archive of each mod, so that a broken download
+	// never supersedes a working archive
+	newest := map[string]*modlist.Archive{}
+	var readable []*modlist.Archive
+
+	for _, archive := range archives {
+		if _, e := modinfo.ReadZip(filepath.Join(dir, archive.File)); e != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s (%v)", archive.File, e))
+
+			continue
+		}
+
+		readable = append(readable, archive)
+
+		if n, ok := newest[archive.Mod]; !ok || archive.Semver.Cmp(n.Semver) > 0 {
+			newest[archive.Mod] = archive
+		}
+	}
+
+	for _, archive := range readable {
+		if list.Get(archive.Mod) == nil {
+			files = append(files, &cleanFile{name: archive.File, reason: "not in " + modlist.FILE_NAME})
+		} else if newest[archive.Mod] != archive {
+			files = append(files, &cleanFile{name: archive.File, reason: "superseded by " + newest[archive.Mod].Version})
+		}
+	}
+
+	return files, unreadable, nil
+}
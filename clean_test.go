@@ -0,0 +1,95 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+// write a mod archive containing only info.json
+func writeModZip(t *testing.T, dir, name, version string) {
+	file, e := os.Create(filepath.Join(dir, name+"_"+version+".zip"))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer file.Close()
+	w := zip.NewWriter(file)
+	f, e := w.Create(name + "_" + version + "/info.json")
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	f.Write([]byte(`{"name": "` + name + `", "version": "` + version + `"}`))
+
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestFindCleanFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeModZip(t, dir, "foo", "1.0.0")
+	writeModZip(t, dir, "bar", "1.0.0")
+	writeModZip(t, dir, "bar", "1.1.0")
+	writeModZip(t, dir, "orphan", "1.0.0")
+
+	// a broken download of a newer foo must not supersede the working archive
+	e := os.WriteFile(filepath.Join(dir, "foo_1.1.0.zip"), []byte("partial"), modlist.MODE)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	e = os.WriteFile(filepath.Join(dir, "notamod.zip"), nil, modlist.MODE)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	e = os.WriteFile(modlist.Path(dir), []byte(`{"mods":[{"name":"foo","enabled":true},{"name":"bar","enabled":true}]}`), modlist.MODE)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	matchAny, _ := common.NewSemver(common.MATCH_ANY)
+	list, e := modlist.Read(dir, matchAny)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	files, unreadable, e := findCleanFiles(dir, list)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	expected := map[string]string{
+		"bar_1.0.0.zip":    "superseded by 1.1.0",
+		"orphan_1.0.0.zip": "not in " + modlist.FILE_NAME,
+		"notamod.zip":      "not named <mod>_<version>.zip",
+	}
+
+	if len(files) != len(expected) {
+		t.Errorf("findCleanFiles() found %d files, expected %d", len(files), len(expected))
+	}
+
+	for _, file := range files {
+		if reason, ok := expected[file.name]; !ok || reason != file.reason {
+			t.Errorf("findCleanFiles() %s: %q, expected %q", file.name, file.reason, reason)
+		}
+	}
+
+	if len(unreadable) != 1 || !strings.HasPrefix(unreadable[0], "foo_1.1.0.zip") {
+		t.Errorf("findCleanFiles() unreadable: %v, expected foo_1.1.0.zip", unreadable)
+	}
+}
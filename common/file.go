@@ -0,0 +1,266 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	STATE_DIR      = ".modtorio" // modtorio's own files within a mods directory
+	STATE_DIR_MODE = 0755
+	TEMP_PREFIX    = ".modtorio-"
+	TEMP_SUFFIX    = ".tmp"
+	STALE_TEMP_AGE = time.Hour // temp files older than this are left over from a crash
+)
+
+// Get the path of a file in the state directory of a mods directory.
+func StatePath(dir string, elem ...string) string {
+	return filepath.Join(append([]string{dir, STATE_DIR}, elem...)...)
+}
+
+// Create the state directory (and any sub-directories given) within a mods
+// directory. The mods directory itself must already exist.
+func MkStateDir(dir string, elem ...string) (string, error) {
+	e := os.Mkdir(StatePath(dir), STATE_DIR_MODE)
+
+	if e != nil && !os.IsExist(e) {
+		return "", e
+	}
+
+	path := StatePath(dir, elem...)
+
+	return path, os.MkdirAll(path, STATE_DIR_MODE)
+}
+
+// Write data to path atomically. See WriteAtomic.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteAtomic(path, bytes.NewReader(data), perm)
+}
+
+// Write the contents of r to path atomically. The data is written to a
+// temporary file in the same directory, synced to disk, and renamed over
+// path. If anything fails path is left untouched.
+func WriteAtomic(path string, r io.Reader, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, e := os.CreateTemp(dir, TEMP_PREFIX+"*"+TEMP_SUFFIX)
+
+	if e != nil {
+		return e
+	}
+
+	// remove the temp file if anything goes wrong. after a successful
+	// rename this fails harmlessly
+	defer os.Remove(tmp.Name())
+
+	_, e = io.Copy(tmp, r)
+
+	if e == nil {
+		e = tmp.Chmod(perm)
+	}
+
+	if e == nil {
+		e = tmp.Sync()
+	}
+
+	if e != nil {
+		tmp.Close()
+
+		return e
+	}
+
+	e = tmp.Close()
+
+	if e != nil {
+		return e
+	}
+
+	e = os.Rename(tmp.Name(), path)
+
+	if e != nil {
+		return e
+	}
+
+	return SyncDir(dir)
+}
+
+// Sync a directory so that renames within it are persisted. Errors from
+// platforms that cannot sync directories are ignored.
+func SyncDir(dir string) error {
+	d, e := os.Open(dir)
+
+	if e != nil {
+		return e
+	}
+
+	defer d.Close()
+	d.Sync()
+
+	return nil
+}
+
+// Remove temp files (and directories) left behind in dir by an interrupted
+// write. Only those older than STALE_TEMP_AGE are removed so that writes in
+// progress by another run are not disturbed.
+func RemoveStaleTemp(dir string) error {
+	entries, e := os.ReadDir(dir)
+
+	if e != nil {
+		return e
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if !IsTemp(name) {
+			continue
+		}
+
+		info, e := entry.Info()
+
+		if e != nil {
+			// removed in the meantime
+			continue
+		}
+
+		if time.Since(info.ModTime()) > STALE_TEMP_AGE {
+			os.RemoveAll(filepath.Join(dir, name))
+		}
+	}
+
+	return nil
+}
+
+// check if a file name is a temp file created by WriteAtomic
+func IsTemp(name string) bool {
+	return strings.HasPrefix(name, TEMP_PREFIX) && strings.HasSuffix(name, TEMP_SUFFIX)
+}
+
+// Format a size in bytes for display (eg. 1.5 MiB).
+func FormatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// Atomically put a copy of src at dst. If hardLink is true src is hard linked
+// where possible (for files that are never modified in place, such as mod
+// archives), otherwise it is always copied. Nothing is done if dst is already
+// a link to src.
+func Place(src, dst string, hardLink bool) error {
+	info, e := os.Stat(src)
+
+	if e != nil {
+		return e
+	}
+
+	if hardLink {
+		if dstInfo, e := os.Stat(dst); e == nil && os.SameFile(info, dstInfo) {
+			// unchanged
+			return nil
+		}
+
+		// reserve a temp name to link to (links cannot replace files)
+		file, e := os.CreateTemp(filepath.Dir(dst), TEMP_PREFIX+"*"+TEMP_SUFFIX)
+
+		if e != nil {
+			return e
+		}
+
+		tmp := file.Name()
+		file.Close()
+		os.Remove(tmp)
+
+		if os.Link(src, tmp) == nil {
+			return os.Rename(tmp, dst)
+		}
+	}
+
+	file, e := os.Open(src)
+
+	if e != nil {
+		return e
+	}
+
+	defer file.Close()
+
+	return WriteAtomic(dst, file, info.Mode().Perm())
+}
+
+// Hard link src to dst, or copy it if linking is not possible
+// (eg. a different file system).
+func LinkOrCopy(src, dst string) error {
+	if os.Link(src, dst) == nil {
+		return nil
+	}
+
+	info, e := os.Stat(src)
+
+	if e != nil {
+		return e
+	}
+
+	return CopyFile(src, dst, info.Mode().Perm())
+}
+
+// Copy src to dst. Fails if dst already exists.
+func CopyFile(src, dst string, perm os.FileMode) error {
+	in, e := os.Open(src)
+
+	if e != nil {
+		return e
+	}
+
+	defer in.Close()
+	out, e := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+
+	if e != nil {
+		return e
+	}
+
+	_, e = io.Copy(out, in)
+
+	if e != nil {
+		out.Close()
+
+		return e
+	}
+
+	return out.Close()
+}
+
+// Get the hex encoded SHA1 checksum of a file.
+func Sha1File(path string) (string, error) {
+	file, e := os.Open(path)
+
+	if e != nil {
+		return "", e
+	}
+
+	defer file.Close()
+	hash := sha1.New()
+	_, e = io.Copy(hash, file)
+
+	if e != nil {
+		return "", e
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
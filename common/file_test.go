@@ -0,0 +1,80 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mod-list.json")
+
+	for _, data := range []string{"first", "second"} {
+		e := WriteFileAtomic(path, []byte(data), 0644)
+
+		if e != nil {
+			t.Fatal("TestWriteFileAtomic:", e)
+		}
+
+		actual, e := os.ReadFile(path)
+
+		if e != nil {
+			t.Fatal("TestWriteFileAtomic:", e)
+		}
+
+		if string(actual) != data {
+			t.Errorf("WriteFileAtomic(%s) wrote: %s", data, actual)
+		}
+	}
+
+	// no temp files should be left behind
+	entries, e := os.ReadDir(dir)
+
+	if e != nil {
+		t.Fatal("TestWriteFileAtomic:", e)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("WriteFileAtomic() left %d files, expected: 1", len(entries))
+	}
+}
+
+func TestRemoveStaleTemp(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, TEMP_PREFIX+"1"+TEMP_SUFFIX)
+	fresh := filepath.Join(dir, TEMP_PREFIX+"2"+TEMP_SUFFIX)
+	other := filepath.Join(dir, "mod_1.0.0.zip")
+
+	for _, path := range []string{stale, fresh, other} {
+		e := os.WriteFile(path, nil, 0644)
+
+		if e != nil {
+			t.Fatal("TestRemoveStaleTemp:", e)
+		}
+	}
+
+	old := time.Now().Add(-2 * STALE_TEMP_AGE)
+	e := os.Chtimes(stale, old, old)
+
+	if e != nil {
+		t.Fatal("TestRemoveStaleTemp:", e)
+	}
+
+	e = RemoveStaleTemp(dir)
+
+	if e != nil {
+		t.Fatal("TestRemoveStaleTemp:", e)
+	}
+
+	if _, e := os.Stat(stale); !os.IsNotExist(e) {
+		t.Errorf("RemoveStaleTemp() did not remove %s", stale)
+	}
+
+	for _, path := range []string{fresh, other} {
+		if _, e := os.Stat(path); e != nil {
+			t.Errorf("RemoveStaleTemp() removed %s", path)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modlist"
+	"github.com/blacksfk/modtorio/output"
+)
+
+const (
+	M_FLAG_VERSIONS = "versions"
+	M_FLAG_SEARCH   = "search"
+	M_FLAG_CSV      = "csv"
+	COMPAT_VERSIONS = "0.17,0.18,1.0,1.1,2.0"
+	COMPAT_NONE     = "-" // no release for a game version
+)
+
+// the newest release of a mod for each game version
+type compatRow struct {
+	Name     string             `json:"name"`
+	Releases map[string]*string `json:"releases"` // nil if there is no release
+}
+
+// print the newest release of mods for each game version
+func compat(flags *ModtorioFlags, options []string) error {
+	var versions, search string
+	var asCSV bool
+
+	compatFlags := flag.NewFlagSet("Compat flags", flag.ContinueOnError)
+
+	compatFlags.StringVar(&versions, M_FLAG_VERSIONS, COMPAT_VERSIONS, "Comma separated game versions")
+	compatFlags.StringVar(&search, M_FLAG_SEARCH, "", "Compare the mods whose name or title match a regular expression")
+	compatFlags.BoolVar(&asCSV, M_FLAG_CSV, false, "Print the matrix as CSV instead of a table")
+	e := compatFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	columns := strings.Split(versions, ",")
+	semvers := make([]*common.Semver, len(columns))
+
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column)
+		semvers[i], e = common.NewSemver(columns[i])
+
+		if e != nil {
+			return e
+		}
+	}
+
+	results, e := compatResults(flags, search, compatFlags.Args())
+
+	if e != nil {
+		return e
+	}
+
+	rows := []*compatRow{}
+
+	for _, result := range results {
+		row := &compatRow{result.Name, map[string]*string{}}
+
+		for i, semver := range semvers {
+			row.Releases[columns[i]] = nil
+
+			if release := newestRelease(results, result.Name, semver); release != nil {
+				row.Releases[columns[i]] = &release.Version
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if output.Structured(flags.output) {
+		// written by main in the global output format
+		emit(rows)
+
+		return nil
+	}
+
+	if asCSV {
+		return printCompatCSV(rows, columns)
+	}
+
+	printCompatTable(rows, columns)
+
+	return nil
+}
+
+// get the mods to compare: those named, those matching the search, or the
+// mods in the mod list
+func compatResults(flags *ModtorioFlags, search string, names []string) ([]*api.Result, error) {
+	if search != "" {
+		sre, e := newSearchRE(search, matchNameOrTitle)
+
+		if e != nil {
+			return nil, e
+		}
+
+		all, e := api.GetAll()
+
+		if e != nil {
+			return nil, e
+		}
+
+		names = nil
+
+		for _, result := range all {
+			if sre.match(result) {
+				names = append(names, result.Name)
+			}
+		}
+
+		if len(names) == 0 {
+			return nil, fmt.Errorf("No mods match %s", search)
+		}
+
+		// the full list does not include releases, so fetch the
+		// matching mods again by name
+		return api.GetAll(names...)
+	}
+
+	if len(names) == 0 {
+		list, e := modlist.Read(flags.dir, flags.factorio)
+
+		if e != nil {
+			return nil, e
+		}
+
+		names = list.GetAllModNames()
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No mods to compare")
+	}
+
+	return api.GetAll(names...)
+}
+
+// get a cell of the matrix
+func compatCell(version *string) string {
+	if version == nil {
+		return COMPAT_NONE
+	}
+
+	return *version
+}
+
+func printCompatTable(rows []*compatRow, columns []string) {
+	// default to 4 for "Name" header
+	longest := 4
+	widths := make([]int, len(columns))
+
+	for _, row := range rows {
+		if l := len(row.Name); l > longest {
+			longest = l
+		}
+	}
+
+	for i, column := range columns {
+		widths[i] = len(column)
+
+		for _, row := range rows {
+			if l := len(compatCell(row.Releases[column])); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+
+	// print a line of the table without trailing padding
+	printLine := func(name string, cell func(int) string) {
+		b := strings.Builder{}
+		fmt.Fprintf(&b, "%-*s", longest, name)
+
+		for i := range columns {
+			fmt.Fprintf(&b, "  %-*s", widths[i], cell(i))
+		}
+
+		fmt.Println(strings.TrimRight(b.String(), " "))
+	}
+
+	printLine("Name", func(i int) string {
+		return columns[i]
+	})
+
+	for _, row := range rows {
+		printLine(row.Name, func(i int) string {
+			return compatCell(row.Releases[columns[i]])
+		})
+	}
+}
+
+func printCompatCSV(rows []*compatRow, columns []string) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write(append([]string{"name"}, columns...))
+
+	for _, row := range rows {
+		record := []string{row.Name}
+
+		for _, column := range columns {
+			version := ""
+
+			if v := row.Releases[column]; v != nil {
+				version = *v
+			}
+
+			record = append(record, version)
+		}
+
+		w.Write(record)
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
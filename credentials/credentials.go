@@ -7,6 +7,8 @@ package credentials
 import (
 	"encoding/json"
 	"os"
+
+	"github.com/blacksfk/modtorio/common"
 )
 
 const (
@@ -55,5 +57,5 @@ func (c *Credentials) ToCache() error {
 		return e
 	}
 
-	return os.WriteFile(CACHE, bytes, MODE)
+	return common.WriteFileAtomic(CACHE, bytes, MODE)
 }
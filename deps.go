@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/blacksfk/modtorio/modinfo"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+// required dependencies of the installed mods, by mod name
+type depGraph map[string][]string
+
+// read the required dependencies of every mod with an archive.
+// unreadable archives are reported and skipped
+func readDependencies(dir string, list *modlist.ModList) (depGraph, error) {
+	e := list.FindArchives(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	deps := depGraph{}
+
+	for _, mod := range list.Mods {
+		if mod.Archive == nil {
+			// not installed, so nothing to read
+			continue
+		}
+
+		info, e := modinfo.ReadZip(filepath.Join(dir, mod.Archive.File))
+
+		if e == nil {
+			deps[mod.Name], e = info.Required()
+		}
+
+		if e != nil {
+			fmt.Printf("Warning: could not read the dependencies of %s: %v\n", mod.Name, e)
+		}
+	}
+
+	return deps, nil
+}
+
+// get the mods (other than those given) that must also be enabled for the
+// given mods to load: their required dependencies, recursively. also returns
+// the names of any required dependencies that are not in the mod list
+func (deps depGraph) required(list *modlist.ModList, mods []*modlist.Mod) ([]*modlist.Mod, []string) {
+	var found []*modlist.Mod
+	var missing []string
+	seen := map[string]bool{}
+	queue := make([]string, 0, len(mods))
+
+	for _, mod := range mods {
+		seen[mod.Name] = true
+		queue = append(queue, mod.Name)
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, dep := range deps[name] {
+			if seen[dep] || dep == modlist.BASE {
+				continue
+			}
+
+			seen[dep] = true
+			mod := list.Get(dep)
+
+			if mod == nil {
+				missing = append(missing, fmt.Sprintf("%s (required by %s)", dep, name))
+				continue
+			}
+
+			if !mod.Enabled {
+				found = append(found, mod)
+			}
+
+			queue = append(queue, dep)
+		}
+	}
+
+	return found, missing
+}
+
+// get the enabled mods (other than those given) that require any of the given
+// mods, recursively. these cannot load once the given mods are disabled
+func (deps depGraph) dependents(list *modlist.ModList, mods []*modlist.Mod) []*modlist.Mod {
+	var found []*modlist.Mod
+	disabled := map[string]bool{}
+
+	for _, mod := range mods {
+		disabled[mod.Name] = true
+	}
+
+	// keep sweeping the list until no more dependents are found
+	for changed := true; changed; {
+		changed = false
+
+		for _, mod := range list.Mods {
+			if !mod.Enabled || disabled[mod.Name] {
+				continue
+			}
+
+			for _, dep := range deps[mod.Name] {
+				if disabled[dep] {
+					disabled[mod.Name] = true
+					found = append(found, mod)
+					changed = true
+
+					break
+				}
+			}
+		}
+	}
+
+	return found
+}
+
+// get the mods (other than those given) that are only required by the given
+// mods, recursively. these are no longer needed once the given mods are removed
+func (deps depGraph) orphans(list *modlist.ModList, mods []*modlist.Mod) []*modlist.Mod {
+	var found []*modlist.Mod
+	removed := map[string]bool{}
+
+	for _, mod := range mods {
+		removed[mod.Name] = true
+	}
+
+	// check if anything not being removed requires a mod
+	requiredElsewhere := func(name string) bool {
+		for _, mod := range list.Mods {
+			if removed[mod.Name] {
+				continue
+			}
+
+			for _, dep := range deps[mod.Name] {
+				if dep == name {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	// keep sweeping the dependencies of removed mods until no more orphans are found
+	for changed := true; changed; {
+		changed = false
+
+		for _, mod := range list.Mods {
+			if removed[mod.Name] || !deps.requiredByAny(mod.Name, removed) || requiredElsewhere(mod.Name) {
+				continue
+			}
+
+			removed[mod.Name] = true
+			found = append(found, mod)
+			changed = true
+		}
+	}
+
+	return found
+}
+
+// check if any of the mods in the set require a mod
+func (deps depGraph) requiredByAny(name string, mods map[string]bool) bool {
+	for mod := range mods {
+		for _, dep := range deps[mod] {
+			if dep == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/credentials"
+	"github.com/blacksfk/modtorio/factorio"
+	"github.com/blacksfk/modtorio/modinfo"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	D_FLAG_ONLINE = "online"
+)
+
+// the checks made by doctor and counts of the problems found
+type diagnosis struct {
+	checks             []*doctorCheck
+	warnings, failures int
+}
+
+type doctorCheck struct {
+	Status  string `json:"status"` // pass, warn or fail
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+func (d *diagnosis) pass(format string, a ...interface{}) {
+	d.report("pass", "", format, a...)
+}
+
+func (d *diagnosis) warn(fix, format string, a ...interface{}) {
+	d.warnings++
+	d.report("warn", fix, format, a...)
+}
+
+func (d *diagnosis) fail(fix, format string, a ...interface{}) {
+	d.failures++
+	d.report("fail", fix, format, a...)
+}
+
+// record and print the outcome of a check
+func (d *diagnosis) report(status, fix, format string, a ...interface{}) {
+	check := &doctorCheck{status, fmt.Sprintf(format, a...), fix}
+	d.checks = append(d.checks, check)
+	fmt.Printf("%-4s  %s\n", strings.ToUpper(status), check.Message)
+
+	if fix != "" {
+		fmt.Printf("      fix: %s\n", fix)
+	}
+}
+
+// diagnose common problems with the mods directory, credentials and
+// (optionally) the installed mods' releases on the mod portal
+func doctor(flags *ModtorioFlags, options []string) error {
+	var online bool
+
+	doctorFlags := flag.NewFlagSet("Doctor flags", flag.ContinueOnError)
+
+	doctorFlags.BoolVar(&online, D_FLAG_ONLINE, false, "Also check the installed mods against the mod portal")
+	e := doctorFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	d := &diagnosis{checks: []*doctorCheck{}}
+	list := d.checkDir(flags)
+
+	d.checkCredentials()
+	d.checkFactorio(flags)
+
+	if list != nil {
+		infos := d.checkArchives(flags, list)
+
+		if online {
+			d.checkPortal(flags, list, infos)
+		}
+	}
+
+	emit(d.checks)
+	fmt.Printf("\n%d warnings, %d failures\n", d.warnings, d.failures)
+
+	if d.failures > 0 {
+		return fmt.Errorf("Doctor found %d failures", d.failures)
+	}
+
+	return nil
+}
+
+// check the mods directory and mod list. returns the mod list if it could be read
+func (d *diagnosis) checkDir(flags *ModtorioFlags) *modlist.ModList {
+	stat, e := os.Stat(flags.dir)
+
+	if e != nil || !stat.IsDir() {
+		d.fail("pass --dir with the factorio mods directory, eg. --dir ~/.factorio/mods", "%s is not a directory", flags.dir)
+
+		return nil
+	}
+
+	d.pass("%s is a directory", flags.dir)
+	bytes, e := os.ReadFile(modlist.Path(flags.dir))
+
+	if os.IsNotExist(e) {
+		d.warn("check --dir is the mods directory, or start factorio once to create "+modlist.FILE_NAME,
+			"%s has no %s", flags.dir, modlist.FILE_NAME)
+
+		return nil
+	} else if e != nil {
+		d.fail("check the permissions of "+modlist.Path(flags.dir), "%s is unreadable: %v", modlist.FILE_NAME, e)
+
+		return nil
+	}
+
+	list, e := modlist.Parse(bytes, flags.factorio)
+
+	if e != nil {
+		d.fail("restore a snapshot with modtorio restore, or delete it and let factorio recreate it",
+			"%s is invalid: %v", modlist.FILE_NAME, e)
+
+		return nil
+	}
+
+	d.pass("%s is valid (%d mods)", modlist.FILE_NAME, len(list.Mods))
+	base := list.GetBuiltin(modlist.BASE)
+
+	if base == nil {
+		d.warn("edit mod-list.json and add base with enabled set to true", "%s has no %s entry", modlist.FILE_NAME, modlist.BASE)
+	} else if !base.Enabled {
+		d.fail("edit mod-list.json and set base enabled to true", "%s is disabled, so no other mod can load", modlist.BASE)
+	} else {
+		d.pass("%s is enabled", modlist.BASE)
+	}
+
+	if p, e := factorio.FindRunning(flags.dir, flags.pidFile); e == nil && p != nil {
+		d.warn("stop factorio before changing mods, or pass --force", "factorio is running with these mods (%v)", p)
+	}
+
+	return list
+}
+
+// check the cached login
+func (d *diagnosis) checkCredentials() {
+	creds, e := credentials.FromCache()
+
+	if os.IsNotExist(e) {
+		d.warn("you will be asked to log in on the next download", "not logged in to factorio.com (no %s)", credentials.CACHE)
+	} else if e != nil {
+		d.fail("delete "+credentials.CACHE+" and log in again on the next download", "%s is invalid: %v", credentials.CACHE, e)
+	} else if creds.Username == "" || creds.Token == "" {
+		d.fail("delete "+credentials.CACHE+" and log in again on the next download", "%s has no username or token", credentials.CACHE)
+	} else {
+		d.pass("logged in to factorio.com as %s", creds.Username)
+	}
+}
+
+// check the factorio version releases are compared against
+func (d *diagnosis) checkFactorio(flags *ModtorioFlags) {
+	if flags.factorio.Major == -1 {
+		d.warn("pass --factorio with the game version, eg. --factorio 1.1",
+			"no --factorio version, so releases for any game version may be downloaded")
+	} else {
+		d.pass("comparing releases against factorio %v", flags.factorio)
+	}
+}
+
+// check the archives in the directory against the mod list and the factorio
+// version. returns the info.json of each mod's installed archive by name
+func (d *diagnosis) checkArchives(flags *ModtorioFlags, list *modlist.ModList) map[string]*modinfo.Info {
+	infos := map[string]*modinfo.Info{}
+	archives, unparseable, e := modlist.ScanArchives(flags.dir)
+
+	if e != nil {
+		d.fail("check the permissions of "+flags.dir, "cannot read %s: %v", flags.dir, e)
+
+		return infos
+	}
+
+	e = list.FindArchives(flags.dir)
+
+	if e != nil {
+		d.fail("check the permissions of "+flags.dir, "cannot read %s: %v", flags.dir, e)
+
+		return infos
+	}
+
+	for _, name := range unparseable {
+		d.warn("rename it to <mod>_<version>.zip, or run modtorio clean", "%s is not named <mod>_<version>.zip", name)
+	}
+
+	versions := map[string][]string{}
+
+	for _, archive := range archives {
+		versions[archive.Mod] = append(versions[archive.Mod], archive.Version)
+
+		if list.Get(archive.Mod) == nil {
+			d.warn("run modtorio adopt, or modtorio clean to delete it", "%s is not in %s", archive.File, modlist.FILE_NAME)
+		}
+	}
+
+	for _, archive := range archives {
+		if v := versions[archive.Mod]; len(v) > 1 && archive.Version == v[0] {
+			d.warn("run modtorio clean to delete the old versions", "%s has %d archives: %s", archive.Mod, len(v), strings.Join(v, " "))
+		}
+	}
+
+	for _, mod := range list.Mods {
+		if mod.Archive == nil {
+			d.warn("run modtorio repair to download it", "%s is in %s but has no archive", mod.Name, modlist.FILE_NAME)
+			continue
+		}
+
+		info, e := modinfo.ReadZip(filepath.Join(flags.dir, mod.Archive.File))
+
+		if e != nil {
+			d.fail("run modtorio repair to download it again", "%s is unreadable: %v", mod.Archive.File, e)
+			continue
+		}
+
+		infos[mod.Name] = info
+
+		if c, e := flags.factorio.CmpString(info.Factorio_version); e != nil {
+			d.warn("check the mod's info.json", "%s has an invalid factorio_version: %q", mod.Archive.File, info.Factorio_version)
+		} else if c != 0 {
+			d.warn("run modtorio update, or download a release for factorio "+flags.factorio.String(),
+				"%s is for factorio %s", mod.Archive.File, info.Factorio_version)
+		}
+	}
+
+	if len(unparseable) == 0 && len(archives) == len(infos) {
+		d.pass("%d archives match %s", len(archives), modlist.FILE_NAME)
+	}
+
+	return infos
+}
+
+// check the installed mods are on the mod portal and up to date
+func (d *diagnosis) checkPortal(flags *ModtorioFlags, list *modlist.ModList, infos map[string]*modinfo.Info) {
+	names := list.GetAllModNames()
+
+	if len(names) == 0 {
+		return
+	}
+
+	results, e := api.GetAll(names...)
+
+	if e != nil {
+		d.fail("check your internet connection", "cannot reach the mod portal: %v", e)
+
+		return
+	}
+
+	d.pass("reached the mod portal")
+
+	for _, name := range names {
+		release := newestRelease(results, name, flags.factorio)
+		info := infos[name]
+
+		if release == nil {
+			d.warn("disable or remove it, or check --factorio", "%s has no release for factorio %v on the mod portal", name, flags.factorio)
+		} else if info != nil && release.CmpVersion(semverOf(info)) > 0 {
+			d.warn("run modtorio update", "%s %s is out of date (latest %s)", name, info.Version, release.Version)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/blacksfk/modtorio/api"
 	"github.com/blacksfk/modtorio/credentials"
 	"github.com/blacksfk/modtorio/modlist"
+	"github.com/blacksfk/modtorio/stage"
 	"golang.org/x/term"
 )
 
@@ -18,6 +19,19 @@ const (
 )
 
 func download(flags *ModtorioFlags, options []string) error {
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	// find existing archives so that they are replaced by the downloads
+	e = list.FindArchives(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
 	// get the mod results for each mod
 	results, e := api.GetAll(options...)
 
@@ -26,33 +40,30 @@ func download(flags *ModtorioFlags, options []string) error {
 	}
 
 	var downloads []*api.Release
-	var toBeEnabled []string
+	var replaced, toBeEnabled []string
 
 	for _, result := range results {
-		found := false
-
-		for i := len(result.Releases) - 1; i >= 0; i-- {
-			if result.Releases[i].CmpFactorioVersion(flags.factorio) == 0 {
-				found = true
-				downloads = append(downloads, result.Releases[i])
-				toBeEnabled = append(toBeEnabled, result.Name)
-				break
-			}
-		}
+		release := newestRelease(results, result.Name, flags.factorio)
 
-		if !found {
+		if release == nil {
 			fmt.Printf("No matching factorio version (%v) found for mod %s\n", flags.factorio, result.Name)
+			continue
 		}
-	}
 
-	e = downloadReleases(flags.dir, downloads)
+		downloads = append(downloads, release)
+		toBeEnabled = append(toBeEnabled, result.Name)
+	}
 
-	if e != nil {
-		return e
+	for _, mod := range list.Mods {
+		if mod.Archive != nil && contains(toBeEnabled, mod.Name) {
+			replaced = append(replaced, mod.Archive.File)
+		}
 	}
 
-	// enable (or add) all downloaded releases
-	return modlist.Add(flags.dir, toBeEnabled...)
+	// enable (or add) all downloaded releases once they are in place
+	list.Add(toBeEnabled...)
+
+	return downloadReleases(flags.dir, downloads, replaced, list, nil)
 }
 
 func attemptLogin() (*credentials.Credentials, error) {
@@ -118,8 +129,29 @@ func promptForCreds() (*credentials.Credentials, error) {
 	return credentials.NewCredentials(username, string(bytes)), nil
 }
 
+// prompt the user to continue. returns true for "yes" or an
+// empty string (linefeed)
+func confirm() (bool, error) {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Printf("Continue? (Y/n): ")
+	scanner.Scan()
+
+	if e := scanner.Err(); e != nil {
+		return false, e
+	}
+
+	answer := scanner.Text()
+
+	return len(answer) == 0 || strings.ToLower(answer)[0] == 'y', nil
+}
+
 // Download the releases. Authenticates the user prior to downloading.
-func downloadReleases(dir string, releases []*api.Release) error {
+// The releases are downloaded to a staging area and only once all of them
+// have succeeded are they moved into dir, the replaced archives removed, and
+// the mod list written (if not nil). beforeCommit (if not nil) is called
+// once the downloads succeed, just before they are moved into dir. On
+// failure dir is left untouched.
+func downloadReleases(dir string, releases []*api.Release, replaced []string, list *modlist.ModList, beforeCommit func() error) error {
 	count := len(releases)
 
 	if count == 0 {
@@ -134,18 +166,14 @@ func downloadReleases(dir string, releases []*api.Release) error {
 	}
 
 	// prompt the user for confirmation of the releases to be downloaded
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Printf("\n\nContinue? (Y/n): ")
-	scanner.Scan()
+	fmt.Printf("\n\n")
+	ok, e := confirm()
 
-	if e := scanner.Err(); e != nil {
+	if e != nil {
 		return e
 	}
 
-	answer := scanner.Text()
-
-	// cancel downloads if not "yes" or empty string (linefeed)
-	if len(answer) > 0 && strings.ToLower(answer)[0] != 'y' {
+	if !ok {
 		return fmt.Errorf("Downloads cancelled")
 	}
 
@@ -158,16 +186,48 @@ func downloadReleases(dir string, releases []*api.Release) error {
 
 	fmt.Println()
 
+	s, e := stage.New(dir)
+
+	if e != nil {
+		return e
+	}
+
+	// remove the staging area if anything fails
+	defer s.Abort()
+
 	// download all of the releases
 	for i := 0; i < count; i++ {
 		fmt.Printf("Downloading %s...", releases[i].File_name)
-		e = releases[i].Download(dir, creds)
+		e = releases[i].Download(s.Path, creds)
+
+		if e != nil {
+			fmt.Println("failed")
+
+			return e
+		}
+
 		fmt.Println("done")
+	}
+
+	if beforeCommit != nil {
+		e = beforeCommit()
 
 		if e != nil {
 			return e
 		}
 	}
 
+	// all downloads succeeded, move them into place
+	fmt.Print("Installing...")
+	e = s.Commit(replaced, list)
+
+	if e != nil {
+		fmt.Println("failed")
+
+		return e
+	}
+
+	fmt.Println("done")
+
 	return nil
 }
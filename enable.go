@@ -1,9 +1,173 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"strings"
+
 	"github.com/blacksfk/modtorio/modlist"
 )
 
+const (
+	E_FLAG_REGEX   = "regex"
+	E_FLAG_GLOB    = "glob"
+	E_FLAG_DRY_RUN = "dry-run"
+	E_FLAG_NO_DEPS = "no-deps"
+)
+
 func enable(flags *ModtorioFlags, options []string) error {
-	return modlist.SetStatus(flags.dir, true, options)
+	return setStatus(flags, options, true)
+}
+
+// set the enabled status of the mods matching the arguments.
+// arguments are mod names unless --regex or --glob is given.
+// enabling a mod also enables its required dependencies, and disabling
+// a mod also disables the mods that require it, unless --no-deps is given
+func setStatus(flags *ModtorioFlags, options []string, enabled bool) error {
+	var regex, glob, dryRun, noDeps bool
+
+	statusFlags := flag.NewFlagSet("Status flags", flag.ContinueOnError)
+
+	statusFlags.BoolVar(&regex, E_FLAG_REGEX, false, "Match mods by regular expression")
+	statusFlags.BoolVar(&glob, E_FLAG_GLOB, false, "Match mods by glob")
+	statusFlags.BoolVar(&dryRun, E_FLAG_DRY_RUN, false, "Show the affected mods without changing them")
+	statusFlags.BoolVar(&noDeps, E_FLAG_NO_DEPS, false, "Do not enable dependencies or disable dependents")
+	e := statusFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	patterns := statusFlags.Args()
+	mode := modlist.MATCH_EXACT
+
+	if len(patterns) == 0 {
+		return fmt.Errorf("No mods specified")
+	}
+
+	if regex && glob {
+		return fmt.Errorf("Only one of --%s and --%s can be used", E_FLAG_REGEX, E_FLAG_GLOB)
+	} else if regex {
+		mode = modlist.MATCH_REGEX
+	} else if glob {
+		mode = modlist.MATCH_GLOB
+	}
+
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	mods, unmatched, e := list.Match(mode, patterns)
+
+	if e != nil {
+		return e
+	}
+
+	if len(unmatched) > 0 {
+		// nothing is changed unless every pattern matched
+		return fmt.Errorf("Not found in the mod list: %s", strings.Join(unmatched, " "))
+	}
+
+	// only mods with a different status are affected
+	var affected []*modlist.Mod
+	var names []string
+
+	for _, mod := range mods {
+		if mod.Enabled != enabled {
+			affected = append(affected, mod)
+			names = append(names, mod.Name)
+		}
+	}
+
+	// mods affected through dependencies
+	var extra []*modlist.Mod
+	deps, e := readDependencies(flags.dir, list)
+
+	if e != nil {
+		return e
+	}
+
+	if enabled {
+		var missing []string
+		extra, missing = deps.required(list, mods)
+
+		for _, m := range missing {
+			fmt.Printf("Warning: %s is not in the mod list\n", m)
+		}
+	} else {
+		extra = deps.dependents(list, affected)
+	}
+
+	if noDeps {
+		// only warn about the mods that will fail to load
+		for _, mod := range extra {
+			if enabled {
+				fmt.Printf("Warning: %s is required but will remain disabled\n", mod.Name)
+			} else {
+				fmt.Printf("Warning: %s requires a disabled mod and will fail to load\n", mod.Name)
+			}
+		}
+
+		extra = nil
+	}
+
+	verb, state, reason := "Enabling", "enabled", "required dependencies"
+
+	if !enabled {
+		verb, state, reason = "Disabling", "disabled", "dependents"
+	}
+
+	if len(affected) == 0 && len(extra) == 0 {
+		fmt.Printf("All matching mods are already %s\n", state)
+
+		return nil
+	}
+
+	var extraNames []string
+
+	for _, mod := range extra {
+		extraNames = append(extraNames, mod.Name)
+	}
+
+	printNames(verb, names)
+	printNames(fmt.Sprintf("%s %s", verb, reason), extraNames)
+	affected = append(affected, extra...)
+
+	for _, mod := range affected {
+		if modlist.IsDLC(mod.Name) {
+			if enabled {
+				fmt.Printf(modlist.DLC_ENABLE_WARNING, mod.Name)
+			} else {
+				fmt.Printf(modlist.DLC_DISABLE_WARNING, mod.Name)
+			}
+		}
+	}
+
+	if dryRun {
+		fmt.Println("Dry run: no changes made")
+
+		return nil
+	}
+
+	if len(extra) > 0 {
+		// confirm the changes beyond what was asked for
+		fmt.Println()
+		ok, e := confirm()
+
+		if e != nil {
+			return e
+		}
+
+		if !ok {
+			return fmt.Errorf("Cancelled")
+		}
+	}
+
+	for _, mod := range affected {
+		mod.Enabled = enabled
+	}
+
+	return list.Write(flags.dir)
 }
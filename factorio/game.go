@@ -0,0 +1,160 @@
+package factorio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/blacksfk/modtorio/common"
+)
+
+const (
+	GAMES_DIR     = "games"    // within modtorio's data directory
+	CURRENT       = "current"  // symlink to the active game version
+	GAME_DIR      = "factorio" // top level folder of a headless tarball
+	GAMES_DIR_ENV = "MODTORIO_GAMES"
+)
+
+// game versions that can be installed, eg. 1.1.110
+var gameVersionRe = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// Get the directory holding the installed headless servers: $MODTORIO_GAMES,
+// or <data home>/modtorio/games. Each version is unpacked into a directory
+// named after it, next to a symlink to the active version.
+func GamesRoot() string {
+	if dir := os.Getenv(GAMES_DIR_ENV); dir != "" {
+		return dir
+	}
+
+	data := os.Getenv("XDG_DATA_HOME")
+
+	if data == "" {
+		home, _ := os.UserHomeDir()
+		data = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(data, "modtorio", GAMES_DIR)
+}
+
+// Check if a string is a game version that can be installed.
+func IsGameVersion(version string) bool {
+	return gameVersionRe.MatchString(version)
+}
+
+// Get the directory of an installed game version within root.
+func GamePath(root, version string) string {
+	return filepath.Join(root, version, GAME_DIR)
+}
+
+// Get the installed game versions, oldest first.
+func Games(root string) ([]*Install, error) {
+	entries, e := os.ReadDir(root)
+
+	if os.IsNotExist(e) {
+		return nil, nil
+	} else if e != nil {
+		return nil, e
+	}
+
+	home, _ := os.UserHomeDir()
+	var games []*Install
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !IsGameVersion(entry.Name()) {
+			continue
+		}
+
+		if install := readInstall(GamePath(root, entry.Name()), systemWriteData(home)); install != nil {
+			games = append(games, install)
+		}
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].Version.Cmp(games[j].Version) < 0
+	})
+
+	return games, nil
+}
+
+// Get the active game version. Returns an empty string if there is none.
+func CurrentGame(root string) string {
+	target, e := os.Readlink(filepath.Join(root, CURRENT))
+
+	if e != nil {
+		return ""
+	}
+
+	return filepath.Base(target)
+}
+
+// Get the installation of the active game version. Returns nil if there is none.
+func ActiveGame() *Install {
+	home, _ := os.UserHomeDir()
+
+	return readInstall(filepath.Join(GamesRoot(), CURRENT, GAME_DIR), systemWriteData(home))
+}
+
+// Make an installed game version the active one.
+func UseGame(root, version string) error {
+	if !isDir(GamePath(root, version)) {
+		return fmt.Errorf("Factorio %s is not installed", version)
+	}
+
+	// replace the symlink atomically
+	tmp := filepath.Join(root, common.TEMP_PREFIX+CURRENT+common.TEMP_SUFFIX)
+	os.Remove(tmp)
+	e := os.Symlink(version, tmp)
+
+	if e != nil {
+		return e
+	}
+
+	e = os.Rename(tmp, filepath.Join(root, CURRENT))
+
+	if e != nil {
+		os.Remove(tmp)
+	}
+
+	return e
+}
+
+// Unpack a headless server tarball into root as a game version. The tarball
+// is unpacked beside the installed versions and only moved into place once
+// it is complete. Requires tar with xz support.
+func UnpackGame(tarball, root, version string) error {
+	e := os.MkdirAll(root, common.STATE_DIR_MODE)
+
+	if e != nil {
+		return e
+	}
+
+	tmp, e := os.MkdirTemp(root, common.TEMP_PREFIX+"*"+common.TEMP_SUFFIX)
+
+	if e != nil {
+		return e
+	}
+
+	defer os.RemoveAll(tmp)
+	out, e := exec.Command("tar", "-xJf", tarball, "-C", tmp).CombinedOutput()
+
+	if e != nil {
+		return fmt.Errorf("Unpacking %s: %v: %s", tarball, e, out)
+	}
+
+	if install := readInstall(filepath.Join(tmp, GAME_DIR), ""); install == nil {
+		return fmt.Errorf("%s is not a factorio server", tarball)
+	} else if install.Version.String() != version {
+		return fmt.Errorf("%s contains factorio %v, not %s", tarball, install.Version, version)
+	}
+
+	e = os.Chmod(tmp, common.STATE_DIR_MODE)
+
+	if e != nil {
+		return e
+	}
+
+	return os.Rename(tmp, filepath.Join(root, version))
+}
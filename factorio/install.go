@@ -0,0 +1,262 @@
+package factorio
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modinfo"
+)
+
+const (
+	CONFIG_PATH_FILE = "config-path.cfg" // points at the config directory
+	CONFIG_FILE      = "config.ini"
+	STEAM_APP_DIR    = "steamapps"
+	STEAM_LIBRARIES  = "libraryfolders.vdf"
+
+	// placeholders used in config-path.cfg and config.ini
+	PATH_EXECUTABLE  = "__PATH__executable__"
+	PATH_SYSTEM_READ = "__PATH__system-read-data__"
+	PATH_SYSTEM_DATA = "__PATH__system-write-data__"
+)
+
+// base mod within an installation, whose info.json holds the game version
+var baseInfo = filepath.Join("data", "base", modinfo.INFO_FILE)
+
+// library paths in a steam libraryfolders.vdf
+var steamLibraryRe = regexp.MustCompile(`"path"\s+"([^"]+)"`)
+
+// a factorio installation found on this machine
+type Install struct {
+	Dir       string         // installation directory, containing data and bin
+	WriteData string         // where saves, config and mods are written
+	Mods      string         // mods directory
+	Version   *common.Semver // game version, eg. 1.1.110
+}
+
+// Find factorio installations in the usual places: the active headless
+// server (see GamesRoot), the standalone installation directories, steam
+// libraries and the system write-data directory. The write-data and mods directories are read from each
+// installation's config. Installations are returned in order of preference.
+func Detect() []*Install {
+	home, _ := os.UserHomeDir()
+
+	return detect(installDirs(home), systemWriteData(home))
+}
+
+// Find the installation using a mods directory. Returns nil if there is none.
+func FindMods(installs []*Install, dir string) *Install {
+	dir, e := canonical(dir)
+
+	if e != nil {
+		return nil
+	}
+
+	for _, install := range installs {
+		if mods, e := canonical(install.Mods); e == nil && mods == dir {
+			return install
+		}
+	}
+
+	return nil
+}
+
+// find installations within the candidate directories. if none are found
+// but the system write-data directory has a mods directory, it is returned
+// without a version
+func detect(candidates []string, systemData string) []*Install {
+	var installs []*Install
+	seen := map[string]bool{}
+
+	for _, dir := range candidates {
+		dir, e := canonical(dir)
+
+		if e != nil || seen[dir] {
+			continue
+		}
+
+		seen[dir] = true
+		install := readInstall(dir, systemData)
+
+		if install != nil {
+			installs = append(installs, install)
+		}
+	}
+
+	mods := filepath.Join(systemData, DEFAULT_MODS)
+
+	if len(installs) == 0 && isDir(mods) {
+		installs = append(installs, &Install{WriteData: systemData, Mods: mods})
+	}
+
+	return installs
+}
+
+// read an installation's version and directories. returns nil if dir is
+// not a factorio installation
+func readInstall(dir, systemData string) *Install {
+	bytes, e := os.ReadFile(filepath.Join(dir, baseInfo))
+
+	if e != nil {
+		return nil
+	}
+
+	info, e := modinfo.Parse(bytes)
+
+	if e != nil {
+		return nil
+	}
+
+	version, e := common.NewSemver(info.Version)
+
+	if e != nil {
+		return nil
+	}
+
+	install := &Install{Dir: dir, Version: version}
+	install.WriteData = writeData(dir, systemData)
+	install.Mods = filepath.Join(install.WriteData, DEFAULT_MODS)
+
+	return install
+}
+
+// determine the write-data directory of an installation from its
+// config-path.cfg and config.ini
+func writeData(dir, systemData string) string {
+	// installations use the system directories by default
+	configDir := filepath.Join(systemData, "config")
+	data := systemData
+	cfg, _ := readConfig(filepath.Join(dir, CONFIG_PATH_FILE))
+
+	if cfg["use-system-read-write-data-directories"] == "false" {
+		configDir = filepath.Join(dir, "config")
+		data = dir
+	}
+
+	if path, ok := cfg["config-path"]; ok {
+		configDir = expandPath(path, dir, systemData)
+	}
+
+	ini, _ := readConfig(filepath.Join(configDir, CONFIG_FILE))
+
+	if path, ok := ini["write-data"]; ok {
+		data = expandPath(path, dir, systemData)
+	}
+
+	return data
+}
+
+// replace the placeholders in a path from factorio's config
+func expandPath(path, dir, systemData string) string {
+	path = strings.Replace(path, PATH_EXECUTABLE, executableDir(dir), 1)
+	path = strings.Replace(path, PATH_SYSTEM_READ, dir, 1)
+	path = strings.Replace(path, PATH_SYSTEM_DATA, systemData, 1)
+
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+// read the key=value pairs of an ini style file, ignoring sections and comments
+func readConfig(path string) (map[string]string, error) {
+	file, e := os.Open(path)
+
+	if e != nil {
+		return nil, e
+	}
+
+	defer file.Close()
+
+	config := map[string]string{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == ';' || line[0] == '#' || line[0] == '[' {
+			continue
+		}
+
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+			config[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return config, scanner.Err()
+}
+
+// the usual installation directories for the platform, including every
+// steam library
+func installDirs(home string) []string {
+	// the active headless server managed by modtorio comes first
+	dirs := []string{filepath.Join(GamesRoot(), CURRENT, GAME_DIR)}
+	var steam []string
+
+	switch runtime.GOOS {
+	case "windows":
+		programs := os.Getenv("ProgramFiles")
+		dirs = append(dirs, filepath.Join(programs, "Factorio"))
+		steam = append(steam, filepath.Join(os.Getenv("ProgramFiles(x86)"), "Steam"))
+	case "darwin":
+		support := filepath.Join(home, "Library", "Application Support")
+		dirs = append(dirs, "/Applications/factorio.app/Contents")
+		steam = append(steam, filepath.Join(support, "Steam"))
+	default:
+		dirs = append(dirs, "/opt/factorio", filepath.Join(home, "factorio"), filepath.Join(home, ".factorio"))
+		steam = append(steam, filepath.Join(home, ".steam", "steam"), filepath.Join(home, ".local", "share", "Steam"))
+	}
+
+	for _, root := range steam {
+		for _, library := range steamLibraries(root) {
+			dirs = append(dirs, filepath.Join(library, STEAM_APP_DIR, "common", "Factorio"))
+		}
+	}
+
+	return dirs
+}
+
+// get the library directories of a steam installation, including itself
+func steamLibraries(root string) []string {
+	libraries := []string{root}
+	bytes, e := os.ReadFile(filepath.Join(root, STEAM_APP_DIR, STEAM_LIBRARIES))
+
+	if e != nil {
+		return libraries
+	}
+
+	for _, match := range steamLibraryRe.FindAllStringSubmatch(string(bytes), -1) {
+		// backslashes are escaped in windows paths
+		libraries = append(libraries, strings.ReplaceAll(match[1], `\\`, `\`))
+	}
+
+	return libraries
+}
+
+// the directory factorio writes to when using the system directories
+func systemWriteData(home string) string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "Factorio")
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "factorio")
+	default:
+		return filepath.Join(home, ".factorio")
+	}
+}
+
+// the directory containing the executable of an installation
+func executableDir(dir string) string {
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(dir, "MacOS")
+	}
+
+	return filepath.Join(dir, "bin", "x64")
+}
+
+func isDir(path string) bool {
+	stat, e := os.Stat(path)
+
+	return e == nil && stat.IsDir()
+}
@@ -0,0 +1,123 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package factorio
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// create a file, and its directories, within dir
+func writeFile(t *testing.T, dir, name, contents string) {
+	path := filepath.Join(dir, name)
+	e := os.MkdirAll(filepath.Dir(path), 0755)
+
+	if e == nil {
+		e = os.WriteFile(path, []byte(contents), 0644)
+	}
+
+	if e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	root := t.TempDir()
+	systemData := filepath.Join(root, "home", ".factorio")
+	standalone := filepath.Join(root, "opt", "factorio")
+	steam := filepath.Join(root, "steam", "Factorio")
+	custom := filepath.Join(root, "srv", "factorio")
+	info := `{"name": "base", "version": "1.1.110"}`
+
+	// a standalone installation writing to its own directory
+	writeFile(t, standalone, baseInfo, info)
+	writeFile(t, standalone, CONFIG_PATH_FILE, "config-path=__PATH__executable__/../../config\nuse-system-read-write-data-directories=false\n")
+
+	// an installation using the system directories, with write-data moved
+	writeFile(t, steam, baseInfo, `{"name": "base", "version": "2.0.15"}`)
+	writeFile(t, systemData, filepath.Join("config", CONFIG_FILE), "; comment\n[path]\nread-data=__PATH__system-read-data__/data\nwrite-data="+custom+"\n")
+
+	installs := detect([]string{standalone, filepath.Join(root, "missing"), steam, standalone}, systemData)
+
+	if len(installs) != 2 {
+		t.Fatalf("detect() found %d installations, expected 2", len(installs))
+	}
+
+	tests := []struct {
+		install       *Install
+		mods, version string
+	}{
+		{installs[0], filepath.Join(standalone, DEFAULT_MODS), "1.1.110"},
+		{installs[1], filepath.Join(custom, DEFAULT_MODS), "2.0.15"},
+	}
+
+	for _, test := range tests {
+		if test.install.Mods != test.mods {
+			t.Errorf("Install.Mods = %s, expected %s", test.install.Mods, test.mods)
+		}
+
+		if v := test.install.Version.String(); v != test.version {
+			t.Errorf("Install.Version = %s, expected %s", v, test.version)
+		}
+	}
+
+	if found := FindMods(installs, filepath.Join(custom, DEFAULT_MODS)); found != installs[1] {
+		t.Errorf("FindMods() = %v, expected %v", found, installs[1])
+	}
+
+	// only a mods directory in the system write-data directory
+	os.MkdirAll(filepath.Join(systemData, DEFAULT_MODS), 0755)
+	installs = detect(nil, systemData)
+
+	if len(installs) != 1 || installs[0].Version != nil {
+		t.Errorf("detect() without installations = %v, expected the system mods directory", installs)
+	}
+}
+
+func TestGames(t *testing.T) {
+	if _, e := exec.LookPath("tar"); e != nil {
+		t.Skip("tar not found")
+	}
+
+	src := t.TempDir()
+	root := filepath.Join(t.TempDir(), GAMES_DIR)
+	tarball := filepath.Join(src, "headless.tar.xz")
+	writeFile(t, src, filepath.Join(GAME_DIR, baseInfo), `{"name": "base", "version": "1.1.110"}`)
+
+	if out, e := exec.Command("tar", "-cJf", tarball, "-C", src, GAME_DIR).CombinedOutput(); e != nil {
+		t.Skipf("tar without xz support: %s", out)
+	}
+
+	if e := UnpackGame(tarball, root, "2.0.0"); e == nil {
+		t.Error("UnpackGame() with the wrong version succeeded")
+	}
+
+	if e := UnpackGame(tarball, root, "1.1.110"); e != nil {
+		t.Fatal("UnpackGame():", e)
+	}
+
+	if current := CurrentGame(root); current != "" {
+		t.Errorf("CurrentGame() = %s before UseGame()", current)
+	}
+
+	if e := UseGame(root, "2.0.0"); e == nil {
+		t.Error("UseGame() with a version that is not installed succeeded")
+	}
+
+	if e := UseGame(root, "1.1.110"); e != nil {
+		t.Fatal("UseGame():", e)
+	}
+
+	if current := CurrentGame(root); current != "1.1.110" {
+		t.Errorf("CurrentGame() = %s, expected 1.1.110", current)
+	}
+
+	games, e := Games(root)
+
+	if e != nil || len(games) != 1 || games[0].Version.String() != "1.1.110" {
+		t.Errorf("Games() = %v, %v, expected 1.1.110", games, e)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Package for interacting with factorio installations and processes.
+*/
+package factorio
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/blacksfk/modtorio/lock"
+)
+
+const (
+	PROC          = "/proc"
+	EXECUTABLE    = "factorio"
+	MOD_DIR_FLAG  = "--mod-directory"
+	LOCK_FILE     = ".lock" // held in the write-data directory while the game runs
+	DEFAULT_MODS  = "mods"  // mods directory within the write-data directory
+	INSTALL_DEPTH = 3       // <install>/bin/x64/factorio
+)
+
+// a running factorio process
+type Process struct {
+	Pid     int      // 0 if only the write-data lock was found
+	Cmdline []string // command line of the process (if known)
+}
+
+func (p *Process) String() string {
+	if p.Pid == 0 {
+		return "lock file held"
+	}
+
+	return fmt.Sprintf("pid %d: %s", p.Pid, strings.Join(p.Cmdline, " "))
+}
+
+// Find a running factorio process using a mods directory. Processes are
+// found by scanning /proc for factorio executables using the directory
+// (by --mod-directory, or by default within their installation), by
+// checking the lock factorio holds on <write-data>/.lock, and by the
+// process in pidFile (if not empty). Returns nil if nothing was found.
+func FindRunning(modsDir, pidFile string) (*Process, error) {
+	modsDir, e := canonical(modsDir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if pidFile != "" {
+		p, e := fromPidFile(pidFile)
+
+		if p != nil || e != nil {
+			return p, e
+		}
+	}
+
+	p, e := scanProc(modsDir)
+
+	if p != nil || e != nil {
+		return p, e
+	}
+
+	// the mods directory is usually within the write-data directory
+	locked, e := lock.IsLocked(filepath.Join(filepath.Dir(modsDir), LOCK_FILE))
+
+	if e != nil || !locked {
+		return nil, e
+	}
+
+	return &Process{}, nil
+}
+
+// check the process in a pid file is alive
+func fromPidFile(path string) (*Process, error) {
+	bytes, e := os.ReadFile(path)
+
+	if e != nil {
+		if os.IsNotExist(e) {
+			// server is not running
+			return nil, nil
+		}
+
+		return nil, e
+	}
+
+	pid, e := strconv.Atoi(strings.TrimSpace(string(bytes)))
+
+	if e != nil {
+		return nil, fmt.Errorf("Invalid pid file %s: %v", path, e)
+	}
+
+	cmdline, e := readCmdline(pid)
+
+	if e != nil {
+		// process is gone, the pid file is stale
+		return nil, nil
+	}
+
+	return &Process{pid, cmdline}, nil
+}
+
+// scan /proc for factorio processes using the mods directory. returns
+// nil on systems without /proc
+func scanProc(modsDir string) (*Process, error) {
+	entries, e := os.ReadDir(PROC)
+
+	if e != nil {
+		return nil, nil
+	}
+
+	for _, entry := range entries {
+		pid, e := strconv.Atoi(entry.Name())
+
+		if e != nil {
+			// not a process
+			continue
+		}
+
+		cmdline, e := readCmdline(pid)
+
+		if e != nil || len(cmdline) == 0 || filepath.Base(cmdline[0]) != EXECUTABLE {
+			// exited in the meantime, a kernel thread, or something else
+			continue
+		}
+
+		dir := processModsDir(pid, cmdline)
+
+		if dir != "" && dir == modsDir {
+			return &Process{pid, cmdline}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// read the command line arguments of a process
+func readCmdline(pid int) ([]string, error) {
+	raw, e := os.ReadFile(filepath.Join(PROC, strconv.Itoa(pid), "cmdline"))
+
+	if e != nil {
+		return nil, e
+	}
+
+	var args []string
+
+	for _, arg := range bytes.Split(bytes.TrimRight(raw, "\x00"), []byte{0}) {
+		args = append(args, string(arg))
+	}
+
+	return args, nil
+}
+
+// determine the mods directory of a factorio process. returns an empty
+// string if it could not be determined
+func processModsDir(pid int, cmdline []string) string {
+	root := filepath.Join(PROC, strconv.Itoa(pid))
+	dir := ""
+
+	for i, arg := range cmdline {
+		if arg == MOD_DIR_FLAG && i+1 < len(cmdline) {
+			dir = cmdline[i+1]
+		} else if strings.HasPrefix(arg, MOD_DIR_FLAG+"=") {
+			dir = strings.TrimPrefix(arg, MOD_DIR_FLAG+"=")
+		}
+	}
+
+	if dir == "" {
+		// default to the mods directory of the installation
+		exe, e := os.Readlink(filepath.Join(root, "exe"))
+
+		if e != nil {
+			return ""
+		}
+
+		for i := 0; i < INSTALL_DEPTH; i++ {
+			exe = filepath.Dir(exe)
+		}
+
+		dir = filepath.Join(exe, DEFAULT_MODS)
+	} else if !filepath.IsAbs(dir) {
+		// relative to the working directory of the process
+		cwd, e := os.Readlink(filepath.Join(root, "cwd"))
+
+		if e != nil {
+			return ""
+		}
+
+		dir = filepath.Join(cwd, dir)
+	}
+
+	dir, e := canonical(dir)
+
+	if e != nil {
+		return ""
+	}
+
+	return dir
+}
+
+// get the absolute path of a directory with symlinks resolved
+func canonical(dir string) (string, error) {
+	dir, e := filepath.Abs(dir)
+
+	if e != nil {
+		return "", e
+	}
+
+	resolved, e := filepath.EvalSymlinks(dir)
+
+	if e != nil {
+		if os.IsNotExist(e) {
+			// nothing can be using a directory that does not exist
+			return dir, nil
+		}
+
+		return "", e
+	}
+
+	return resolved, nil
+}
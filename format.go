@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	F_FLAG_FORMAT = "format"
+)
+
+// helper functions available to --format templates. the value is the last
+// argument so that they can be used in pipelines, eg. {{.Name | pad 30}}
+var formatFuncs = template.FuncMap{
+	"date":    formatDate,
+	"pad":     func(width int, v interface{}) string { return fmt.Sprintf("%-*v", width, v) },
+	"padLeft": func(width int, v interface{}) string { return fmt.Sprintf("%*v", width, v) },
+	"join":    formatJoin,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+}
+
+// parse a --format template
+func parseFormat(text string) (*template.Template, error) {
+	t, e := template.New(F_FLAG_FORMAT).Funcs(formatFuncs).Parse(text)
+
+	if e != nil {
+		return nil, fmt.Errorf("Invalid format: %v", e)
+	}
+
+	return t, nil
+}
+
+// print an item with a --format template, on a line of its own
+func printFormatted(t *template.Template, item interface{}) error {
+	b := &strings.Builder{}
+	e := t.Execute(b, item)
+
+	if e != nil {
+		return e
+	}
+
+	line := b.String()
+
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+
+	_, e = fmt.Fprint(os.Stdout, line)
+
+	return e
+}
+
+// format a time, or a timestamp from the mod portal, with a Go time layout
+func formatDate(layout string, v interface{}) (string, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case string:
+		parsed, e := time.Parse(time.RFC3339, t)
+
+		if e != nil {
+			return "", e
+		}
+
+		return parsed.Format(layout), nil
+	default:
+		return "", fmt.Errorf("date: %v is not a time", v)
+	}
+}
+
+// join the elements of any slice with a separator
+func formatJoin(sep string, v interface{}) (string, error) {
+	value := reflect.ValueOf(v)
+
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: %v is not a list", v)
+	}
+
+	elements := make([]string, value.Len())
+
+	for i := range elements {
+		elements[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+
+	return strings.Join(elements, sep), nil
+}
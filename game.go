@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/factorio"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	GAME_INSTALL   = "install"
+	GAME_LIST      = "list"
+	GAME_USE       = "use"
+	G_FLAG_USE     = "use"
+	GAME_EXTENSION = ".tar.xz"
+)
+
+// an installed headless server
+type gameEntry struct {
+	Version string `json:"version"`
+	Dir     string `json:"dir"`
+	Active  bool   `json:"active"`
+}
+
+// manage the headless servers in factorio.GamesRoot()
+func game(flags *ModtorioFlags, options []string) error {
+	switch options[0] {
+	case GAME_INSTALL:
+		return gameInstall(options[1:])
+	case GAME_LIST:
+		return gameList()
+	case GAME_USE:
+		if len(options) < 2 {
+			return fmt.Errorf("No game version specified")
+		}
+
+		return gameUse(options[1])
+	default:
+		return fmt.Errorf("Unknown option %s for command game", options[0])
+	}
+}
+
+// download, verify and unpack a headless server. the first server installed
+// becomes the active one
+func gameInstall(options []string) error {
+	var use bool
+
+	installFlags := flag.NewFlagSet("Game install flags", flag.ContinueOnError)
+
+	installFlags.BoolVar(&use, G_FLAG_USE, false, "Make the installed version the active one")
+	e := installFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	if installFlags.NArg() == 0 {
+		return fmt.Errorf("No game version specified")
+	}
+
+	version := installFlags.Arg(0)
+
+	if api.IsChannel(version) {
+		version, e = resolveChannel(version)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	if !factorio.IsGameVersion(version) {
+		return fmt.Errorf("Invalid game version: %s (expected eg. 1.1.110 or stable)", version)
+	}
+
+	root := factorio.GamesRoot()
+
+	if _, e := os.Stat(filepath.Join(root, version)); e == nil {
+		return fmt.Errorf("Factorio %s is already installed", version)
+	}
+
+	e = os.MkdirAll(root, common.STATE_DIR_MODE)
+
+	if e != nil {
+		return e
+	}
+
+	creds, e := attemptLogin()
+
+	if e != nil {
+		return e
+	}
+
+	// named as a temp file so it is cleaned up if modtorio is interrupted
+	tarball := filepath.Join(root, common.TEMP_PREFIX+version+GAME_EXTENSION+common.TEMP_SUFFIX)
+	defer os.Remove(tarball)
+
+	fmt.Printf("Downloading factorio %s headless...", version)
+	e = api.DownloadGame(version, tarball, creds)
+
+	if e != nil {
+		fmt.Println("failed")
+
+		return e
+	}
+
+	fmt.Println("done")
+	fmt.Print("Unpacking...")
+	e = factorio.UnpackGame(tarball, root, version)
+
+	if e != nil {
+		fmt.Println("failed")
+
+		return e
+	}
+
+	fmt.Println("done")
+	fmt.Printf("Installed factorio %s in %s\n", version, factorio.GamePath(root, version))
+
+	if use || factorio.CurrentGame(root) == "" {
+		return gameUse(version)
+	}
+
+	return nil
+}
+
+// list the installed headless servers, marking the active one
+func gameList() error {
+	root := factorio.GamesRoot()
+	games, e := factorio.Games(root)
+
+	if e != nil {
+		return e
+	}
+
+	current := factorio.CurrentGame(root)
+	entries := []*gameEntry{}
+
+	for _, g := range games {
+		entries = append(entries, &gameEntry{g.Version.String(), g.Dir, g.Version.String() == current})
+	}
+
+	emit(entries)
+
+	if len(games) == 0 {
+		fmt.Printf("No headless servers installed in %s\n", root)
+
+		return nil
+	}
+
+	for _, g := range games {
+		marker := " "
+
+		if g.Version.String() == current {
+			marker = "*"
+		}
+
+		fmt.Printf("%s %-10v %s\n", marker, g.Version, g.Dir)
+	}
+
+	return nil
+}
+
+// make an installed headless server the active one
+func gameUse(version string) error {
+	if !factorio.IsGameVersion(version) {
+		return fmt.Errorf("Invalid game version: %s", version)
+	}
+
+	e := factorio.UseGame(factorio.GamesRoot(), version)
+
+	if e != nil {
+		return e
+	}
+
+	fmt.Printf("Using factorio %s\n", version)
+
+	// each version has its own write-data, so commands now default to a
+	// different mods directory
+	if install := factorio.ActiveGame(); install != nil {
+		fmt.Printf("Mods directory: %s\n", install.Mods)
+
+		if _, e := os.Stat(modlist.Path(install.Mods)); e != nil {
+			fmt.Printf("Warning: %s has no %s. Copy your mods there or pass --dir to use another mods directory\n", install.Mods, modlist.FILE_NAME)
+		}
+	}
+
+	return nil
+}
@@ -11,14 +11,42 @@ func help(flags *ModtorioFlags, options []string) error {
 			helpSearch()
 		case CMD_DOWNLOAD:
 			helpDownload()
+		case CMD_INSTALL:
+			helpInstall()
 		case CMD_UPDATE:
 			helpUpdate()
 		case CMD_ENABLE:
 			helpEnable()
 		case CMD_DISABLE:
 			helpDisable()
+		case CMD_REMOVE:
+			helpRemove()
+		case CMD_CLEAN:
+			helpClean()
+		case CMD_ADOPT:
+			helpAdopt()
+		case CMD_INSPECT:
+			helpInspect()
+		case CMD_VERIFY:
+			helpVerify()
+		case CMD_REPAIR:
+			helpRepair()
+		case CMD_DOCTOR:
+			helpDoctor()
 		case CMD_LIST:
 			helpList()
+		case CMD_SNAPSHOT, CMD_SNAPSHOTS, CMD_RESTORE:
+			helpSnapshot()
+		case CMD_HISTORY, CMD_UNDO:
+			helpHistory()
+		case CMD_GAME:
+			helpGame()
+		case CMD_UPGRADE_PLAN:
+			helpUpgradePlan()
+		case CMD_COMPAT:
+			helpCompat()
+		case F_FLAG_FORMAT:
+			helpFormat()
 		case CMD_HELP:
 			helpHelp()
 		default:
@@ -37,16 +65,35 @@ func help(flags *ModtorioFlags, options []string) error {
 func helpAll() {
 	fmt.Printf("usage: modtorio [...flags] <command> [...options] <arguments>\n\n")
 	fmt.Printf("Flags:\n")
-	fmt.Printf("\t--dir\tSpecify the working directory for commands that interact with modlist.json. Leave blank if the current directory contains modlist.json or you want modlist.json to be created in the current directory.\n")
-	fmt.Printf("\t--factorio\tSpecify the factorio version to compare releases against. Defaults to the latest version.\n\n")
+	fmt.Printf("\t--dir\tSpecify the working directory for commands that interact with modlist.json. Defaults to the current directory if it contains modlist.json, otherwise the mods directory of the detected factorio installation (~/.factorio, /opt/factorio, steam libraries), otherwise the current directory. The detected directory is printed to stderr.\n")
+	fmt.Printf("\t--factorio\tSpecify the factorio version to compare releases against. Accepts stable or experimental for the latest headless release on that channel, cached for offline use. Defaults to the version of the installation using the working directory, or any version if none was detected.\n")
+	fmt.Printf("\t--output\tOutput format: text (default), json or yaml. json and yaml write a single document with the command's result to stdout, and everything else to stderr. See the readme for the format.\n")
+	fmt.Printf("\t--timeout\tHow long (eg. 30s, 5m) each request to factorio.com may take, including downloads. No limit by default.\n")
+	fmt.Printf("\t--wait\tHow long to wait (eg. 30s, 5m) for another modtorio process to finish with the working directory. Commands that modify the directory fail immediately by default.\n")
+	fmt.Printf("\t--force\tModify the working directory even if a running factorio process is using it. Swapping mods under a running server can corrupt saves.\n")
+	fmt.Printf("\t--pid-file\tPID file of the factorio server using the working directory. Running servers are also detected via /proc and the lock file in the write-data directory.\n\n")
 	fmt.Printf("Commands:\n")
 	helpHelp()
 	helpSearch()
 	helpDownload()
+	helpInstall()
 	helpUpdate()
 	helpEnable()
 	helpDisable()
+	helpRemove()
+	helpClean()
+	helpAdopt()
+	helpInspect()
+	helpVerify()
+	helpRepair()
+	helpDoctor()
 	helpList()
+	helpFormat()
+	helpSnapshot()
+	helpHistory()
+	helpGame()
+	helpUpgradePlan()
+	helpCompat()
 }
 
 func helpSearch() {
@@ -56,13 +103,15 @@ func helpSearch() {
 	fmt.Printf("\tOptions:\n")
 	fmt.Printf("\t\t--tag\t\tSearch for mods based on a tag\n")
 	fmt.Printf("\t\t--owner\t\tSearch for mods created by a user\n")
-	fmt.Printf("\t\t--name-only\tOnly print out the mod name for matching mods\n")
+	fmt.Printf("\t\t--name-only\tOnly print out the mod name for matching mods, on a single line\n")
+	fmt.Printf("\t\t--format\tPrint each matching mod with a Go text/template. See help format\n")
 	fmt.Printf("\tExamples:\n")
 	fmt.Printf("\t\tmodtorio search ^bob\n")
 	fmt.Printf("\t\tmodtorio search --tag general\n")
 	fmt.Printf("\t\tmodtorio search --owner py.*\n")
 	fmt.Printf("\t\tmodtorio search --name-only angel\n")
 	fmt.Printf("\t\tmodtorio search --owner bobingabout --tag general --name-only\n")
+	fmt.Printf("\t\tmodtorio search --format '{{.Name | pad 30}} {{.Latest_release.Version}} {{.Downloads_count}}' ^bob\n")
 }
 
 func helpDownload() {
@@ -74,48 +123,238 @@ func helpDownload() {
 	fmt.Printf("\t\tmodtorio --factorio 0.17 --dir ~/.config/factorio/mods download bobinserters helicopters\n")
 }
 
+func helpInstall() {
+	// install command
+	fmt.Printf("install\n")
+	fmt.Printf("\tInstall mods from local zip files or HTTP(S) URLs, eg. private mods or beta builds not on the mod portal.\n")
+	fmt.Printf("\tArchives must contain a valid info.json, and are renamed to <name>_<version>.zip, replacing any installed version, and enabled.\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio install ~/private-mod_1.0.0.zip\n")
+	fmt.Printf("\t\tmodtorio --dir ~/.factorio/mods install https://example.com/builds/mymod-beta.zip\n")
+}
+
 func helpUpdate() {
 	// update command
 	fmt.Printf("update\n")
 	fmt.Printf("\tUpdate all mods to their latest release for the factorio version (if specified).\n")
+	fmt.Printf("\tA snapshot is taken before updating, which can be restored with the restore command.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--no-snapshot\t\tDo not take a snapshot before updating\n")
+	fmt.Printf("\t\t--keep-snapshots <n>\tNumber of automatic snapshots to keep, at least 1 (default: %d)\n", KEEP_SNAPSHOTS)
 	fmt.Printf("\tExamples:\n")
 	fmt.Printf("\t\tmodtorio update\n")
 	fmt.Printf("\t\tmodtorio --factorio 0.18 update\n")
 	fmt.Printf("\t\tmodtorio --factorio 0.18 --dir ~/.config/factorio/mods update\n")
+	fmt.Printf("\t\tmodtorio update --keep-snapshots 2\n")
 }
 
 func helpEnable() {
 	// enable command
 	fmt.Printf("enable\n")
-	fmt.Printf("\tEnable mods. Arguments are compiled as regular expressions.\n")
+	fmt.Printf("\tEnable mods. Arguments are mod names unless --regex or --glob is given. Nothing is changed unless every argument matches.\n")
+	fmt.Printf("\tRequired dependencies (read from the installed archives) are also enabled after confirmation.\n")
+	fmt.Printf("\tFactorio 2.0 DLC components (space-age, quality, elevated-rails) can be enabled if the expansion is owned.\n")
+	helpStatusOptions()
 	fmt.Printf("\tExamples:\n")
-	fmt.Printf("\t\tmodtorio enable bob.* pyhightech ^angel\n")
-	fmt.Printf("\t\tmodtorio --dir ~/.config/factorio/mods enable bob.*\n")
+	fmt.Printf("\t\tmodtorio enable bobinserters pyhightech\n")
+	fmt.Printf("\t\tmodtorio enable --glob 'bob*'\n")
+	fmt.Printf("\t\tmodtorio --dir ~/.config/factorio/mods enable --regex --dry-run ^angel\n")
 }
 
 func helpDisable() {
 	// disable command
 	fmt.Printf("disable\n")
-	fmt.Printf("\tDisable mods. Arguments are compiled as regular expressions.\n")
+	fmt.Printf("\tDisable mods. Arguments are mod names unless --regex or --glob is given. Nothing is changed unless every argument matches.\n")
+	fmt.Printf("\tEnabled mods requiring a disabled mod (read from the installed archives) are also disabled after confirmation.\n")
+	fmt.Printf("\tFactorio 2.0 DLC components (space-age, quality, elevated-rails) can be disabled. The base mod cannot.\n")
+	helpStatusOptions()
 	fmt.Printf("\tExamples:\n")
-	fmt.Printf("\t\tmodtorio disable bob.* pyhightech ^angel\n")
-	fmt.Printf("\t\tmodtorio --dir ~/.config/factorio/mods disable bob.*\n")
+	fmt.Printf("\t\tmodtorio disable bobinserters pyhightech\n")
+	fmt.Printf("\t\tmodtorio disable --glob 'bob*'\n")
+	fmt.Printf("\t\tmodtorio --dir ~/.config/factorio/mods disable --regex --dry-run ^angel\n")
+}
+
+func helpRemove() {
+	// remove command
+	fmt.Printf("remove\n")
+	fmt.Printf("\tUninstall mods: delete their archives and remove them from mod-list.json. Arguments are mod names.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--with-orphans\tAlso remove dependencies that no other mod requires\n")
+	fmt.Printf("\t\t--dry-run\tShow the mods that would be removed without removing them\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio remove helicopters\n")
+	fmt.Printf("\t\tmodtorio remove --with-orphans bobwarfare bobvehicleequipment\n")
+}
+
+func helpClean() {
+	// clean command
+	fmt.Printf("clean\n")
+	fmt.Printf("\tRemove archives that are not in mod-list.json, superseded by a newer version of the same mod, or not named <mod>_<version>.zip. Archives that cannot be read are reported for verify and repair instead.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--quarantine\tMove the files to .modtorio/quarantine in the working directory instead of deleting them\n")
+	fmt.Printf("\t\t--dry-run\tOnly report the files that would be cleaned up\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio clean --dry-run\n")
+	fmt.Printf("\t\tmodtorio --dir ~/.factorio/mods clean --quarantine\n")
+}
+
+func helpAdopt() {
+	// adopt command
+	fmt.Printf("adopt\n")
+	fmt.Printf("\tAdd archives copied into the directory by hand to mod-list.json. The mod name and version are read from info.json.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--enabled\tEnable the adopted mods (they are disabled by default)\n")
+	fmt.Printf("\t\t--verify\tCheck the archives are published releases on the mod portal\n")
+	fmt.Printf("\t\t--dry-run\tShow the archives that would be adopted without adopting them\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio adopt\n")
+	fmt.Printf("\t\tmodtorio adopt --enabled --verify\n")
+}
+
+func helpInspect() {
+	// inspect command
+	fmt.Printf("inspect\n")
+	fmt.Printf("\tPrint the info.json metadata, dependencies, files and changelog of an installed mod or a zip file, without extracting it.\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio inspect bobplates\n")
+	fmt.Printf("\t\tmodtorio inspect ~/Downloads/bobplates_1.1.6.zip\n")
+}
+
+func helpVerify() {
+	// verify command
+	fmt.Printf("verify\n")
+	fmt.Printf("\tCheck every archive in the working directory: zip integrity, the top level folder, info.json matching the file name and mod-list.json, and the mod portal checksum.\n")
+	fmt.Printf("\tExits with an error if any problems are found.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--offline\tSkip the mod portal checksum comparison\n")
+}
+
+func helpRepair() {
+	// repair command
+	fmt.Printf("repair\n")
+	fmt.Printf("\tRe-download mods in mod-list.json whose archives fail verify or are missing. The installed version is downloaded again. If there is no archive, the version pinned in mod-list.json is downloaded, or the newest release for the factorio version if none is pinned.\n")
+	fmt.Printf("\tEnabled and disabled mods stay that way.\n")
+}
+
+func helpDoctor() {
+	// doctor command
+	fmt.Printf("doctor\n")
+	fmt.Printf("\tDiagnose common problems: the working directory, mod-list.json, the base mod, cached credentials, the factorio version, and archives that are missing, duplicated or for another game version.\n")
+	fmt.Printf("\tEach check prints PASS, WARN or FAIL with a suggested fix. Exits with an error if any check fails.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--online\tAlso check the installed mods against the mod portal for missing releases and updates\n")
+}
+
+func helpStatusOptions() {
+	// options shared by enable and disable
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--regex\t\tArguments are (unanchored) regular expressions\n")
+	fmt.Printf("\t\t--glob\t\tArguments are globs, eg. bob*\n")
+	fmt.Printf("\t\t--dry-run\tShow the affected mods without changing them\n")
+	fmt.Printf("\t\t--no-deps\tDo not enable required dependencies (enable) or disable the mods that require a mod (disable)\n")
 }
 
 func helpList() {
 	// list command
 	fmt.Printf("list\n")
-	fmt.Printf("\tList mods. Base mod is intentionally left out as it should not be manipulated.\n")
+	fmt.Printf("\tList mods. Base mod and DLC components are intentionally left out as they are built in to the game.\n")
 	fmt.Printf("\tOptions:\n")
 	fmt.Printf("\t\t--all\t\tList all installed mods (default)\n")
 	fmt.Printf("\t\t--enabled\tList all enabled mods\n")
 	fmt.Printf("\t\t--disabled\tList all disabled mods\n")
+	fmt.Printf("\t\t--format\tPrint each mod with a Go text/template. See help format\n")
 	fmt.Printf("\tExamples:\n")
 	fmt.Printf("\t\tmodtorio list\n")
 	fmt.Printf("\t\tmodtorio list --all\n")
 	fmt.Printf("\t\tmodtorio list --enabled\n")
 	fmt.Printf("\t\tmodtorio list --disabled\n")
 	fmt.Printf("\t\tmodtorio --dir ~/.config/factorio/mods list\n")
+	fmt.Printf("\t\tmodtorio list --enabled --format '{{.Name}}@{{.Version}}'\n")
+}
+
+func helpFormat() {
+	// --format option of search and list
+	fmt.Printf("format\n")
+	fmt.Printf("\tThe --format option of search and list prints each mod with a Go text/template (https://pkg.go.dev/text/template), one per line.\n")
+	fmt.Printf("\tsearch mods have the fields of the mod portal API, eg. .Name .Title .Owner .Summary .Category .Downloads_count .Created_at .Latest_release.Version .Latest_release.Info_json.Factorio_version .Releases\n")
+	fmt.Printf("\tlist mods have .Name .Enabled .Version .Archive (empty if the mod has no archive)\n")
+	fmt.Printf("\tFunctions (the value comes last, so they can be used in pipelines):\n")
+	fmt.Printf("\t\tdate <layout> <time>\tFormat a time with a Go layout, eg. {{.Created_at | date \"2006-01-02\"}}\n")
+	fmt.Printf("\t\tpad <width> <value>\tPad a value on the right, eg. {{.Name | pad 30}}\n")
+	fmt.Printf("\t\tpadLeft <width> <value>\tPad a value on the left\n")
+	fmt.Printf("\t\tjoin <sep> <list>\tJoin the values of a list with a separator\n")
+	fmt.Printf("\t\tlower, upper\t\tChange the case of a string\n")
+	fmt.Printf("\t--format '{{.Name}}' replaces --name-only when one mod per line is wanted.\n")
+}
+
+func helpSnapshot() {
+	// snapshot, snapshots and restore commands
+	fmt.Printf("snapshot [name]\n")
+	fmt.Printf("\tSnapshot mod-list.json, mod-settings.dat and all mod archives. Unchanged archives are hard-linked to save space. The name defaults to the current time.\n")
+	fmt.Printf("snapshots\n")
+	fmt.Printf("\tList all snapshots.\n")
+	fmt.Printf("restore <name>\n")
+	fmt.Printf("\tReturn the directory to exactly the state recorded in a snapshot.\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio snapshot before-bobs\n")
+	fmt.Printf("\t\tmodtorio snapshots\n")
+	fmt.Printf("\t\tmodtorio restore before-bobs\n")
+}
+
+func helpHistory() {
+	// history and undo commands
+	fmt.Printf("history [n]\n")
+	fmt.Printf("\tShow the last n (default: %d) operations that modified the directory: who ran what, and what changed.\n", HISTORY_COUNT)
+	fmt.Printf("undo [n]\n")
+	fmt.Printf("\tRevert the last n (default: 1) operations. Refuses if the directory has changed since, unless --force is given.\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio history\n")
+	fmt.Printf("\t\tmodtorio undo\n")
+	fmt.Printf("\t\tmodtorio undo 3\n")
+}
+
+func helpGame() {
+	// game command
+	fmt.Printf("game\n")
+	fmt.Printf("\tManage factorio headless servers, unpacked side by side in $MODTORIO_GAMES (default ~/.local/share/modtorio/games) with a current symlink to the active version.\n")
+	fmt.Printf("\tThe active version is the default --factorio version of other commands, and its mods directory the default --dir.\n")
+	fmt.Printf("\tEach version has its own mods directory (printed by use), so switching versions switches the mods commands act on unless --dir is given.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\tinstall <version|stable|experimental>\tDownload (with your factorio.com login), verify and unpack a headless server. Requires tar with xz support.\n")
+	fmt.Printf("\t\t\t--use\tMake it the active version. The first version installed always is.\n")
+	fmt.Printf("\t\tlist\t\t\t\t\tList the installed versions. The active version is marked with *\n")
+	fmt.Printf("\t\tuse <version>\t\t\t\tMake an installed version the active one\n")
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio game install --use stable\n")
+	fmt.Printf("\t\tmodtorio game use 1.1.110\n")
+}
+
+func helpUpgradePlan() {
+	// upgrade-plan command
+	fmt.Printf("upgrade-plan\n")
+	fmt.Printf("\tCheck every mod in mod-list.json for a release for another game version. Each mod is ready (with the version it would be upgraded to), blocked (no release for the version) or missing (not on the mod portal).\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--to <version>\tGame version to upgrade to, eg. 2.0, stable or experimental. Required\n")
+	fmt.Printf("\t\t--apply\t\tDownload the releases for the target version if every mod is ready\n")
+	fmt.Printf("\t\t--no-snapshot\tDo not take a snapshot before applying the upgrade\n")
+	fmt.Printf("\t\t--keep-snapshots <n>\tNumber of automatic snapshots to keep, at least 1 (default: %d)\n", KEEP_SNAPSHOTS)
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio upgrade-plan --to 2.0\n")
+	fmt.Printf("\t\tmodtorio upgrade-plan --to stable --apply\n")
+}
+
+func helpCompat() {
+	// compat command
+	fmt.Printf("compat\n")
+	fmt.Printf("\tPrint the newest release of mods for each game version. Compares the mods named, the mods matching --search, or the mods in mod-list.json.\n")
+	fmt.Printf("\tOptions:\n")
+	fmt.Printf("\t\t--versions <list>\tComma separated game versions (default: %s)\n", COMPAT_VERSIONS)
+	fmt.Printf("\t\t--search <regexp>\tCompare the mods whose name or title match a regular expression\n")
+	fmt.Printf("\t\t--csv\t\t\tPrint CSV instead of a table. Missing releases are %s in tables and empty in csv\n", COMPAT_NONE)
+	fmt.Printf("\tExamples:\n")
+	fmt.Printf("\t\tmodtorio compat\n")
+	fmt.Printf("\t\tmodtorio compat --versions 1.1,2.0 --csv bobplates angelsrefining\n")
+	fmt.Printf("\t\tmodtorio --output json compat --search ^bob\n")
 }
 
 func helpHelp() {
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blacksfk/modtorio/journal"
+)
+
+const (
+	HISTORY_COUNT = 20 // entries shown by default
+)
+
+// show the most recent journal entries
+func history(flags *ModtorioFlags, options []string) error {
+	count := HISTORY_COUNT
+
+	if len(options) > 0 {
+		var e error
+		count, e = strconv.Atoi(options[0])
+
+		if e != nil || count < 1 {
+			return fmt.Errorf("Invalid entry count: %s", options[0])
+		}
+	}
+
+	entries, e := journal.List(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	if len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+
+	emit(newJournalEntries(entries))
+
+	if len(entries) == 0 {
+		fmt.Println("No history")
+
+		return nil
+	}
+
+	for _, entry := range entries {
+		printEntry(entry)
+	}
+
+	return nil
+}
+
+// revert the most recent operations
+func undo(flags *ModtorioFlags, options []string) error {
+	count := 1
+
+	if len(options) > 0 {
+		var e error
+		count, e = strconv.Atoi(options[0])
+
+		if e != nil || count < 1 {
+			return fmt.Errorf("Invalid operation count: %s", options[0])
+		}
+	}
+
+	undone, e := journal.Undo(flags.dir, count, flags.force)
+	emit(newJournalEntries(undone))
+
+	for _, entry := range undone {
+		fmt.Printf("Undone #%d: modtorio %s\n", entry.Id, strings.Join(entry.Args, " "))
+	}
+
+	return e
+}
+
+// print a journal entry and a summary of its changes
+func printEntry(entry *journal.Entry) {
+	fmt.Printf("#%d  %s  %s  modtorio %s", entry.Id, entry.Time.Format(SNAPSHOT_TIME_FORMAT), entry.User, strings.Join(entry.Args, " "))
+
+	if entry.Undone {
+		fmt.Print("  (undone)")
+	}
+
+	fmt.Println()
+
+	printChanges("mods added", entry.ModsAdded)
+	printChanges("mods removed", entry.ModsRemoved)
+	printChanges("enabled", entry.Enabled)
+	printChanges("disabled", entry.Disabled)
+	printChanges("archives added", entry.Added)
+	printChanges("archives removed", entry.Removed)
+
+	if entry.Error != "" {
+		fmt.Printf("\terror: %s\n", entry.Error)
+	}
+}
+
+func printChanges(label string, names []string) {
+	if len(names) > 0 {
+		fmt.Printf("\t%s: %s\n", label, strings.Join(names, " "))
+	}
+}
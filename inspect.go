@@ -0,0 +1,219 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modinfo"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	CHANGELOG_FILE = "changelog.txt"
+)
+
+// files in a mod's top level folder worth knowing about when debugging
+var inspectFiles = []string{"settings.lua", "settings-updates.lua", "settings-final-fixes.lua", "data-final-fixes.lua", "control.lua"}
+
+// the contents of a mod archive
+type inspectEntry struct {
+	Archive         string          `json:"archive"`
+	Name            string          `json:"name"`
+	Version         string          `json:"version"`
+	Title           string          `json:"title"`
+	Author          string          `json:"author"`
+	Contact         string          `json:"contact"`
+	Homepage        string          `json:"homepage"`
+	FactorioVersion string          `json:"factorio_version"`
+	Description     string          `json:"description"`
+	Dependencies    []*inspectDep   `json:"dependencies"`
+	Files           []*inspectFile  `json:"files"`
+	Scripts         map[string]bool `json:"scripts"` // presence of inspectFiles
+	Changelog       *string         `json:"changelog"`
+}
+
+type inspectDep struct {
+	Dependency string `json:"dependency"`
+	Kind       string `json:"kind"`
+	Error      string `json:"error,omitempty"`
+}
+
+type inspectFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// print the contents of an installed mod's archive, or a given zip file,
+// without extracting it
+func inspect(flags *ModtorioFlags, options []string) error {
+	path, e := inspectPath(flags, options[0])
+
+	if e != nil {
+		return e
+	}
+
+	z, e := zip.OpenReader(path)
+
+	if e != nil {
+		return e
+	}
+
+	defer z.Close()
+
+	info, e := modinfo.Read(&z.Reader)
+
+	if e != nil {
+		return e
+	}
+
+	entry := &inspectEntry{
+		Archive:         path,
+		Name:            info.Name,
+		Version:         info.Version,
+		Title:           info.Title,
+		Author:          info.Author,
+		Contact:         info.Contact,
+		Homepage:        info.Homepage,
+		FactorioVersion: info.Factorio_version,
+		Description:     info.Description,
+		Dependencies:    []*inspectDep{},
+		Files:           []*inspectFile{},
+		Scripts:         map[string]bool{},
+	}
+
+	for _, s := range info.Dependencies {
+		dep, e := modinfo.ParseDependency(s)
+
+		if e != nil {
+			entry.Dependencies = append(entry.Dependencies, &inspectDep{Dependency: s, Error: e.Error()})
+		} else {
+			entry.Dependencies = append(entry.Dependencies, &inspectDep{Dependency: dep.String(), Kind: dep.Kind.String()})
+		}
+	}
+
+	files := map[string]bool{}
+
+	for _, file := range z.File {
+		if !file.FileInfo().IsDir() {
+			entry.Files = append(entry.Files, &inspectFile{file.Name, int64(file.UncompressedSize64)})
+			files[file.Name] = true
+		}
+	}
+
+	// the top level folder containing info.json
+	root := strings.TrimSuffix(modinfo.FindInfo(&z.Reader).Name, modinfo.INFO_FILE)
+
+	for _, name := range inspectFiles {
+		entry.Scripts[name] = files[root+name]
+	}
+
+	if changelog, e := readZipFile(&z.Reader, root+CHANGELOG_FILE); e == nil {
+		entry.Changelog = &changelog
+	}
+
+	emit(entry)
+	printInspectEntry(entry)
+
+	return nil
+}
+
+func printInspectEntry(entry *inspectEntry) {
+	fmt.Printf("Archive:     %s\n", entry.Archive)
+	fmt.Printf("Name:        %s\n", entry.Name)
+	fmt.Printf("Version:     %s\n", entry.Version)
+	fmt.Printf("Title:       %s\n", entry.Title)
+	fmt.Printf("Author:      %s\n", entry.Author)
+	fmt.Printf("Contact:     %s\n", entry.Contact)
+	fmt.Printf("Homepage:    %s\n", entry.Homepage)
+	fmt.Printf("Factorio:    %s\n", entry.FactorioVersion)
+	fmt.Printf("Description: %s\n", entry.Description)
+
+	fmt.Printf("\nDependencies:\n")
+
+	for _, dep := range entry.Dependencies {
+		if dep.Error != "" {
+			fmt.Printf("\t%s (invalid: %s)\n", dep.Dependency, dep.Error)
+		} else {
+			fmt.Printf("\t%-40s %s\n", dep.Dependency, dep.Kind)
+		}
+	}
+
+	fmt.Printf("\nFiles:\n")
+
+	for _, file := range entry.Files {
+		fmt.Printf("\t%10s  %s\n", common.FormatSize(file.Size), file.Name)
+	}
+
+	fmt.Println()
+
+	for _, name := range inspectFiles {
+		fmt.Printf("%-25s %s\n", name+":", yesNo(entry.Scripts[name]))
+	}
+
+	if entry.Changelog == nil {
+		fmt.Printf("\nNo %s\n", CHANGELOG_FILE)
+	} else {
+		fmt.Printf("\nChangelog:\n%s\n", *entry.Changelog)
+	}
+}
+
+// get the path of the archive to inspect: a zip file if it exists, otherwise
+// the installed archive of the named mod
+func inspectPath(flags *ModtorioFlags, arg string) (string, error) {
+	if strings.HasSuffix(arg, modlist.ARCHIVE_EXT) {
+		if _, e := os.Stat(arg); e == nil {
+			return arg, nil
+		}
+	}
+
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return "", e
+	}
+
+	e = list.FindArchives(flags.dir)
+
+	if e != nil {
+		return "", e
+	}
+
+	mod := list.Get(arg)
+
+	if mod == nil {
+		return "", fmt.Errorf("%s is not a zip file or a mod in %s", arg, modlist.FILE_NAME)
+	}
+
+	if mod.Archive == nil {
+		return "", fmt.Errorf("%s has no archive in %s", arg, flags.dir)
+	}
+
+	return filepath.Join(flags.dir, mod.Archive.File), nil
+}
+
+// read the contents of a file within an archive
+func readZipFile(z *zip.Reader, name string) (string, error) {
+	f, e := z.Open(name)
+
+	if e != nil {
+		return "", e
+	}
+
+	defer f.Close()
+	bytes, e := io.ReadAll(f)
+
+	return string(bytes), e
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modinfo"
+	"github.com/blacksfk/modtorio/modlist"
+	"github.com/blacksfk/modtorio/stage"
+)
+
+const (
+	STAGED_FILE = "install.zip" // name of an archive before it is renamed
+)
+
+// install mods from local zip files or HTTP(S) URLs. archives are checked
+// for a valid info.json and renamed to <name>_<version>.zip. all archives
+// are installed (and enabled) together, or not at all
+func install(flags *ModtorioFlags, options []string) error {
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	// find existing archives so that they are replaced
+	e = list.FindArchives(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	s, e := stage.New(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	// remove the staging area if anything fails
+	defer s.Abort()
+
+	var replaced, names []string
+
+	for _, source := range options {
+		info, e := stageArchive(s, source)
+
+		if e != nil {
+			return fmt.Errorf("%s: %v", source, e)
+		}
+
+		if modlist.IsBuiltin(info.Name, flags.factorio) {
+			return fmt.Errorf("%s: %s is built in to the game", source, info.Name)
+		}
+
+		fmt.Printf("Installing %s %s from %s\n", info.Name, info.Version, source)
+		names = append(names, info.Name)
+
+		if mod := list.Get(info.Name); mod != nil && mod.Archive != nil {
+			replaced = append(replaced, mod.Archive.File)
+		}
+	}
+
+	list.Add(names...)
+
+	return s.Commit(replaced, list)
+}
+
+// copy or download an archive into the staging area, check it is a mod and
+// rename it to its canonical name
+func stageArchive(s *stage.Stage, source string) (*modinfo.Info, error) {
+	path := filepath.Join(s.Path, STAGED_FILE)
+	u, e := url.Parse(source)
+
+	if e == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		fmt.Printf("Downloading %s...", source)
+		e = api.DownloadURL(source, path)
+
+		if e != nil {
+			fmt.Println("failed")
+
+			return nil, e
+		}
+
+		fmt.Println("done")
+	} else {
+		e = common.CopyFile(source, path, modlist.MODE)
+
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	info, e := modinfo.ReadZip(path)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if strings.ContainsAny(info.Name+info.Version, `/\`) {
+		return nil, fmt.Errorf("Invalid mod name or version in %s", modinfo.INFO_FILE)
+	}
+
+	canonical := filepath.Join(s.Path, info.Name+"_"+info.Version+modlist.ARCHIVE_EXT)
+
+	if _, e := os.Stat(canonical); e == nil {
+		return nil, fmt.Errorf("%s %s is already being installed", info.Name, info.Version)
+	}
+
+	return info, os.Rename(path, canonical)
+}
@@ -0,0 +1,482 @@
+/*
+Package to record the changes made to a mods directory by each command,
+so that they can be reviewed and undone.
+*/
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modlist"
+	"github.com/blacksfk/modtorio/snapshot"
+)
+
+const (
+	DIR          = "journal"
+	ENTRY        = "entry.json"
+	BEFORE       = "before.json" // mod-list.json before the operation
+	AFTER        = "after.json"  // mod-list.json after the operation
+	ARCHIVES_DIR = "archives"    // archives removed by the operation
+	ID_WIDTH     = 6
+	MODE         = 0644
+)
+
+// an operation recorded in the journal
+type Entry struct {
+	Id    int
+	Time  time.Time
+	User  string
+	Args  []string
+	Error string `json:",omitempty"` // set if the command failed part way through
+
+	// archives added to or removed from the mods directory
+	Added, Removed []string
+
+	// changes to mod-list.json
+	ModsAdded, ModsRemoved []string
+	Enabled, Disabled      []string
+
+	Undone bool
+
+	path string // directory holding the entry
+}
+
+// an operation in progress. see Begin
+type Recorder struct {
+	dir      string
+	tmp      string   // entry directory until the operation ends
+	before   []byte   // mod-list.json, nil if it did not exist
+	archives []string // archives present before the operation
+	started  time.Time
+}
+
+// Start recording an operation on a mods directory. The current mod list is
+// saved, and every archive is hard linked so that removed archives can be
+// restored by Undo.
+func Begin(dir string) (*Recorder, error) {
+	root, e := common.MkStateDir(dir, DIR)
+
+	if e != nil {
+		return nil, e
+	}
+
+	tmp, e := os.MkdirTemp(root, common.TEMP_PREFIX+"*"+common.TEMP_SUFFIX)
+
+	if e != nil {
+		return nil, e
+	}
+
+	r := &Recorder{dir: dir, tmp: tmp, started: time.Now()}
+	r.before, e = readList(dir)
+
+	if e == nil {
+		r.archives, e = snapshot.ListArchives(dir)
+	}
+
+	if e == nil {
+		e = os.Mkdir(filepath.Join(tmp, ARCHIVES_DIR), common.STATE_DIR_MODE)
+	}
+
+	for i := 0; e == nil && i < len(r.archives); i++ {
+		e = common.LinkOrCopy(filepath.Join(dir, r.archives[i]), filepath.Join(tmp, ARCHIVES_DIR, r.archives[i]))
+	}
+
+	if e != nil {
+		os.RemoveAll(tmp)
+
+		return nil, e
+	}
+
+	return r, nil
+}
+
+// Finish recording an operation run with args, which failed with cause (if
+// not nil). The entry is only written if the mods directory changed. Returns
+// the entry, or nil if nothing changed.
+func (r *Recorder) End(args []string, cause error) (*Entry, error) {
+	// the temp directory is renamed to the entry on success
+	defer os.RemoveAll(r.tmp)
+
+	after, e := readList(r.dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	archives, e := snapshot.ListArchives(r.dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	entry := &Entry{Time: r.started, User: currentUser(), Args: args}
+	entry.Added = difference(archives, r.archives)
+	entry.Removed = difference(r.archives, archives)
+	e = entry.diffLists(r.before, after)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if len(entry.Added) == 0 && len(entry.Removed) == 0 && bytes.Equal(r.before, after) {
+		// nothing changed, nothing to record
+		return nil, nil
+	}
+
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+
+	// only keep the archives that were removed
+	for _, archive := range r.archives {
+		if !contains(entry.Removed, archive) {
+			os.Remove(filepath.Join(r.tmp, ARCHIVES_DIR, archive))
+		}
+	}
+
+	e = writeOptional(filepath.Join(r.tmp, BEFORE), r.before)
+
+	if e == nil {
+		e = writeOptional(filepath.Join(r.tmp, AFTER), after)
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	// allocate the next id by renaming the entry into place.
+	// the mods directory is locked, so nothing else is allocating ids
+	entries, e := List(r.dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	entry.Id = 1
+
+	if len(entries) > 0 {
+		entry.Id = entries[len(entries)-1].Id + 1
+	}
+
+	entry.path = filepath.Join(filepath.Dir(r.tmp), formatId(entry.Id))
+	e = entry.write(r.tmp)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return entry, os.Rename(r.tmp, entry.path)
+}
+
+// Get the path of the directory holding the journal (see common.RemoveStaleTemp).
+func Root(dir string) string {
+	return common.StatePath(dir, DIR)
+}
+
+// Get all journal entries of a mods directory, oldest first.
+func List(dir string) ([]*Entry, error) {
+	root := Root(dir)
+	dirEntries, e := os.ReadDir(root)
+
+	if e != nil {
+		if os.IsNotExist(e) {
+			// nothing recorded yet
+			return nil, nil
+		}
+
+		return nil, e
+	}
+
+	var entries []*Entry
+
+	for _, d := range dirEntries {
+		if _, e := strconv.Atoi(d.Name()); e != nil || !d.IsDir() {
+			// an operation in progress or something else
+			continue
+		}
+
+		path := filepath.Join(root, d.Name())
+		bytes, e := os.ReadFile(filepath.Join(path, ENTRY))
+
+		if e != nil {
+			return nil, e
+		}
+
+		entry := &Entry{path: path}
+		e = json.Unmarshal(bytes, entry)
+
+		if e != nil {
+			return nil, fmt.Errorf("Journal entry %s: %v", d.Name(), e)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Id < entries[j].Id
+	})
+
+	return entries, nil
+}
+
+// Undo the last n operations (that have not already been undone), newest
+// first. Unless force is true, an operation is only undone if the mods
+// directory is still in the state the operation left it in. Returns the
+// entries that were undone.
+func Undo(dir string, n int, force bool) ([]*Entry, error) {
+	entries, e := List(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	var undone []*Entry
+
+	for i := len(entries) - 1; i >= 0 && len(undone) < n; i-- {
+		entry := entries[i]
+
+		if entry.Undone {
+			continue
+		}
+
+		e = entry.undo(dir, force)
+
+		if e != nil {
+			return undone, fmt.Errorf("Undo #%d: %v", entry.Id, e)
+		}
+
+		undone = append(undone, entry)
+	}
+
+	if len(undone) == 0 {
+		return nil, fmt.Errorf("Nothing to undo")
+	}
+
+	return undone, nil
+}
+
+// revert the changes of an entry
+func (entry *Entry) undo(dir string, force bool) error {
+	before, e := readOptional(filepath.Join(entry.path, BEFORE))
+
+	if e != nil {
+		return e
+	}
+
+	if !force {
+		e = entry.checkCurrent(dir)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	// restore the removed archives before removing the added ones
+	for _, archive := range entry.Removed {
+		e = common.Place(filepath.Join(entry.path, ARCHIVES_DIR, archive), filepath.Join(dir, archive), true)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	for _, archive := range entry.Added {
+		e = os.Remove(filepath.Join(dir, archive))
+
+		if e != nil && !os.IsNotExist(e) {
+			return e
+		}
+	}
+
+	if before == nil {
+		e = os.Remove(modlist.Path(dir))
+
+		if os.IsNotExist(e) {
+			e = nil
+		}
+	} else {
+		e = common.WriteFileAtomic(modlist.Path(dir), before, modlist.MODE)
+	}
+
+	if e != nil {
+		return e
+	}
+
+	entry.Undone = true
+
+	return entry.write(entry.path)
+}
+
+// check the mods directory is in the state the entry left it in
+func (entry *Entry) checkCurrent(dir string) error {
+	after, e := readOptional(filepath.Join(entry.path, AFTER))
+
+	if e != nil {
+		return e
+	}
+
+	current, e := readList(dir)
+
+	if e != nil {
+		return e
+	}
+
+	if !bytes.Equal(after, current) {
+		return fmt.Errorf("%s has changed since this operation. Use --force to undo anyway", modlist.FILE_NAME)
+	}
+
+	for _, archive := range entry.Added {
+		if _, e := os.Stat(filepath.Join(dir, archive)); e != nil {
+			return fmt.Errorf("%s has been removed since this operation. Use --force to undo anyway", archive)
+		}
+	}
+
+	return nil
+}
+
+// record the changes between two versions of mod-list.json
+func (entry *Entry) diffLists(before, after []byte) error {
+	a, e := parseList(before)
+
+	if e != nil {
+		return e
+	}
+
+	b, e := parseList(after)
+
+	if e != nil {
+		return e
+	}
+
+	for name, enabled := range b {
+		if was, ok := a[name]; !ok {
+			entry.ModsAdded = append(entry.ModsAdded, name)
+		} else if enabled && !was {
+			entry.Enabled = append(entry.Enabled, name)
+		} else if !enabled && was {
+			entry.Disabled = append(entry.Disabled, name)
+		}
+	}
+
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			entry.ModsRemoved = append(entry.ModsRemoved, name)
+		}
+	}
+
+	sort.Strings(entry.ModsAdded)
+	sort.Strings(entry.ModsRemoved)
+	sort.Strings(entry.Enabled)
+	sort.Strings(entry.Disabled)
+
+	return nil
+}
+
+func (entry *Entry) write(dir string) error {
+	bytes, e := json.MarshalIndent(entry, "", "\t")
+
+	if e != nil {
+		return e
+	}
+
+	return common.WriteFileAtomic(filepath.Join(dir, ENTRY), bytes, MODE)
+}
+
+// parse mod-list.json into a map of mod names to enabled status.
+// a missing file is an empty list
+func parseList(bytes []byte) (map[string]bool, error) {
+	mods := map[string]bool{}
+
+	if bytes == nil {
+		return mods, nil
+	}
+
+	list := &modlist.ModList{}
+	e := json.Unmarshal(bytes, list)
+
+	if e != nil {
+		return nil, e
+	}
+
+	for _, mod := range list.Mods {
+		mods[mod.Name] = mod.Enabled
+	}
+
+	return mods, nil
+}
+
+// read mod-list.json in a directory. returns nil if it does not exist
+func readList(dir string) ([]byte, error) {
+	return readOptional(modlist.Path(dir))
+}
+
+// read a file, returning nil if it does not exist
+func readOptional(path string) ([]byte, error) {
+	bytes, e := os.ReadFile(path)
+
+	if os.IsNotExist(e) {
+		return nil, nil
+	}
+
+	return bytes, e
+}
+
+// write a file if data is not nil
+func writeOptional(path string, data []byte) error {
+	if data == nil {
+		return nil
+	}
+
+	return os.WriteFile(path, data, MODE)
+}
+
+// get the name of the user running modtorio (and who ran sudo, if anyone)
+func currentUser() string {
+	name := os.Getenv("USER")
+
+	if u, e := user.Current(); e == nil {
+		name = u.Username
+	}
+
+	if sudo := os.Getenv("SUDO_USER"); sudo != "" && sudo != name {
+		name = fmt.Sprintf("%s (sudo by %s)", name, sudo)
+	}
+
+	return name
+}
+
+func formatId(id int) string {
+	return fmt.Sprintf("%0*d", ID_WIDTH, id)
+}
+
+// get the names in a that are not in b
+func difference(a, b []string) []string {
+	var diff []string
+
+	for _, name := range a {
+		if !contains(b, name) {
+			diff = append(diff, name)
+		}
+	}
+
+	return diff
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+func TestRecordUndo(t *testing.T) {
+	dir := t.TempDir()
+	before := `{"mods":[{"name":"a","enabled":true}]}`
+	after := `{"mods":[{"name":"a","enabled":false},{"name":"b","enabled":true}]}`
+
+	write := func(name, data string) {
+		e := os.WriteFile(filepath.Join(dir, name), []byte(data), MODE)
+
+		if e != nil {
+			t.Fatal("TestRecordUndo:", e)
+		}
+	}
+
+	write(modlist.FILE_NAME, before)
+	write("a_1.0.0.zip", "a")
+
+	r, e := Begin(dir)
+
+	if e != nil {
+		t.Fatal("TestRecordUndo:", e)
+	}
+
+	// replace a and add b
+	os.Remove(filepath.Join(dir, "a_1.0.0.zip"))
+	write("a_1.1.0.zip", "a2")
+	write("b_1.0.0.zip", "b")
+	write(modlist.FILE_NAME, after)
+
+	entry, e := r.End([]string{"test"}, nil)
+
+	if e != nil {
+		t.Fatal("TestRecordUndo:", e)
+	}
+
+	if entry == nil || entry.Id != 1 {
+		t.Fatalf("End() = %v, expected entry #1", entry)
+	}
+
+	if len(entry.Added) != 2 || len(entry.Removed) != 1 || len(entry.Disabled) != 1 || len(entry.ModsAdded) != 1 {
+		t.Errorf("End() recorded the wrong changes: %+v", entry)
+	}
+
+	_, e = Undo(dir, 1, false)
+
+	if e != nil {
+		t.Fatal("TestRecordUndo:", e)
+	}
+
+	bytes, e := os.ReadFile(filepath.Join(dir, modlist.FILE_NAME))
+
+	if e != nil || string(bytes) != before {
+		t.Errorf("Undo() mod-list.json = %s, expected: %s", bytes, before)
+	}
+
+	for name, expected := range map[string]bool{"a_1.0.0.zip": true, "a_1.1.0.zip": false, "b_1.0.0.zip": false} {
+		_, e := os.Stat(filepath.Join(dir, name))
+
+		if (e == nil) != expected {
+			t.Errorf("Undo(): %s exists = %t, expected: %t", name, e == nil, expected)
+		}
+	}
+
+	if _, e = Undo(dir, 1, false); e == nil {
+		t.Error("Undo() with nothing left to undo should fail")
+	}
+}
+
+func TestEndUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	r, e := Begin(dir)
+
+	if e != nil {
+		t.Fatal("TestEndUnchanged:", e)
+	}
+
+	entry, e := r.End([]string{"test"}, nil)
+
+	if e != nil || entry != nil {
+		t.Errorf("End() = %v, %v, expected nothing to be recorded", entry, e)
+	}
+}
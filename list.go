@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/blacksfk/modtorio/modlist"
@@ -10,26 +11,90 @@ const (
 	H_SEP = "-"
 )
 
+// a mod as it is given to --format templates
+type listItem struct {
+	Name    string
+	Enabled bool
+	Version string // version of the installed archive, empty if there is none
+	Archive string
+}
+
 func list(flags *ModtorioFlags, options []string) error {
-	if len(options) > 0 {
-		switch options[0] {
-		case "--all":
-			return listAll(flags.dir)
-		case "--enabled":
-			return listMods(flags.dir, true)
-		case "--disabled":
-			return listMods(flags.dir, false)
-		default:
-			return fmt.Errorf("Unknown option %s for command list", options[0])
-		}
+	var all, enabled, disabled bool
+	var format string
+
+	listFlags := flag.NewFlagSet("List flags", flag.ContinueOnError)
+
+	listFlags.BoolVar(&all, "all", false, "List all mods (default)")
+	listFlags.BoolVar(&enabled, "enabled", false, "List enabled mods")
+	listFlags.BoolVar(&disabled, "disabled", false, "List disabled mods")
+	listFlags.StringVar(&format, F_FLAG_FORMAT, "", "Print each mod with a Go text/template")
+	e := listFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	if listFlags.NArg() > 0 {
+		return fmt.Errorf("Unknown option %s for command list", listFlags.Arg(0))
+	}
+
+	if format != "" {
+		return listFormatted(flags, format, func(mod *modlist.Mod) bool {
+			return all || !enabled && !disabled || mod.Enabled == enabled
+		})
+	}
+
+	if all {
+		return listAll(flags)
+	} else if enabled || disabled {
+		return listMods(flags, enabled)
 	}
 
 	// if no options default to all()
-	return listAll(flags.dir)
+	return listAll(flags)
 }
 
-func listMods(dir string, enabled bool) error {
-	list, e := modlist.Read(dir)
+// print the mods that match with a --format template
+func listFormatted(flags *ModtorioFlags, format string, match func(*modlist.Mod) bool) error {
+	t, e := parseFormat(format)
+
+	if e != nil {
+		return e
+	}
+
+	list, e := readListEntries(flags, match)
+
+	if e != nil {
+		return e
+	}
+
+	for _, mod := range list.Mods {
+		if !match(mod) {
+			continue
+		}
+
+		item := listItem{Name: mod.Name, Enabled: mod.Enabled}
+
+		if mod.Archive != nil {
+			item.Version = mod.Archive.Version
+			item.Archive = mod.Archive.File
+		}
+
+		e = printFormatted(t, item)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func listMods(flags *ModtorioFlags, enabled bool) error {
+	list, e := readListEntries(flags, func(mod *modlist.Mod) bool {
+		return mod.Enabled == enabled
+	})
 
 	if e != nil {
 		return e
@@ -49,8 +114,10 @@ func listMods(dir string, enabled bool) error {
 }
 
 // display all mods by name (column 1) and their status (column 2)
-func listAll(dir string) error {
-	list, e := modlist.Read(dir)
+func listAll(flags *ModtorioFlags) error {
+	list, e := readListEntries(flags, func(mod *modlist.Mod) bool {
+		return true
+	})
 
 	if e != nil {
 		return e
@@ -99,3 +166,30 @@ func printStringTimes(s string, times int) {
 		fmt.Print(s)
 	}
 }
+
+// read the mod list and its archives, emitting the mods that match
+func readListEntries(flags *ModtorioFlags, match func(*modlist.Mod) bool) (*modlist.ModList, error) {
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return nil, e
+	}
+
+	e = list.FindArchives(flags.dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	entries := []*modEntry{}
+
+	for _, mod := range list.Mods {
+		if match(mod) {
+			entries = append(entries, newModEntry(mod))
+		}
+	}
+
+	emit(entries)
+
+	return list, nil
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package lock
+
+import (
+	"os"
+	"syscall"
+)
+
+// take an exclusive flock without blocking
+func tryLock(file *os.File) error {
+	e := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+
+	if e == syscall.EWOULDBLOCK {
+		return errLocked
+	}
+
+	return e
+}
+
+func unlock(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,15 @@
+package lock
+
+import (
+	"os"
+)
+
+// advisory locks are not supported on windows; the lock file is still
+// written so that the holder can be seen by other runs
+func tryLock(file *os.File) error {
+	return nil
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
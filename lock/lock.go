@@ -0,0 +1,185 @@
+/*
+Package to take an advisory lock on a mods directory so that concurrent
+runs of modtorio do not overwrite each other's changes.
+*/
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blacksfk/modtorio/common"
+)
+
+const (
+	FILE_NAME     = "lock"
+	MODE          = 0644
+	POLL_INTERVAL = 250 * time.Millisecond
+)
+
+// returned by tryLock if another process holds the lock
+var errLocked = errors.New("locked")
+
+// the process holding a lock
+type Holder struct {
+	Pid     int
+	Command string
+	Started time.Time
+}
+
+func (h *Holder) String() string {
+	return fmt.Sprintf("pid %d (%s) since %s", h.Pid, h.Command, h.Started.Format(time.RFC1123))
+}
+
+// returned by Acquire if the lock could not be taken in time
+type HeldError struct {
+	Dir    string
+	Holder *Holder // nil if the holder could not be read
+}
+
+func (he *HeldError) Error() string {
+	if he.Holder == nil {
+		return fmt.Sprintf("%s is locked by another modtorio process", he.Dir)
+	}
+
+	return fmt.Sprintf("%s is locked by %v", he.Dir, he.Holder)
+}
+
+type Lock struct {
+	file *os.File
+}
+
+// Take an exclusive lock on a mods directory. If the lock is held by
+// another process, retry until wait has elapsed (or fail immediately if
+// wait is 0). The lock is released if the process exits.
+func Acquire(dir string, wait time.Duration) (*Lock, error) {
+	stateDir, e := common.MkStateDir(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	path := filepath.Join(stateDir, FILE_NAME)
+	file, e := os.OpenFile(path, os.O_RDWR|os.O_CREATE, MODE)
+
+	if e != nil {
+		return nil, e
+	}
+
+	deadline := time.Now().Add(wait)
+
+	for {
+		e = tryLock(file)
+
+		if e == nil {
+			break
+		}
+
+		if e != errLocked || time.Now().After(deadline) {
+			file.Close()
+
+			if e == errLocked {
+				return nil, &HeldError{dir, readHolder(path)}
+			}
+
+			return nil, e
+		}
+
+		time.Sleep(POLL_INTERVAL)
+	}
+
+	// lock acquired, record who holds it
+	l := &Lock{file}
+	e = l.writeHolder()
+
+	if e != nil {
+		l.Release()
+
+		return nil, e
+	}
+
+	return l, nil
+}
+
+// Release the lock.
+func (l *Lock) Release() error {
+	// clear the holder so a stale entry is not reported
+	l.file.Truncate(0)
+	e := unlock(l.file)
+
+	if e != nil {
+		l.file.Close()
+
+		return e
+	}
+
+	return l.file.Close()
+}
+
+func (l *Lock) writeHolder() error {
+	args := append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...)
+	holder := &Holder{os.Getpid(), strings.Join(args, " "), time.Now()}
+	bytes, e := json.Marshal(holder)
+
+	if e != nil {
+		return e
+	}
+
+	e = l.file.Truncate(0)
+
+	if e != nil {
+		return e
+	}
+
+	_, e = l.file.WriteAt(bytes, 0)
+
+	return e
+}
+
+// read the holder of a lock. returns nil if it could not be read
+func readHolder(path string) *Holder {
+	bytes, e := os.ReadFile(path)
+
+	if e != nil {
+		return nil
+	}
+
+	holder := &Holder{}
+
+	if json.Unmarshal(bytes, holder) != nil {
+		return nil
+	}
+
+	return holder
+}
+
+// Check if any process holds a lock on a file (such as the .lock file
+// factorio keeps in its write-data directory while running). Returns
+// false if the file does not exist.
+func IsLocked(path string) (bool, error) {
+	file, e := os.Open(path)
+
+	if e != nil {
+		if os.IsNotExist(e) {
+			return false, nil
+		}
+
+		return false, e
+	}
+
+	defer file.Close()
+	e = tryLock(file)
+
+	if e == errLocked {
+		return true, nil
+	} else if e != nil {
+		return false, e
+	}
+
+	return false, unlock(file)
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package lock
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+	l, e := Acquire(dir, 0)
+
+	if e != nil {
+		t.Fatal("TestAcquire:", e)
+	}
+
+	// a second lock on the same directory must fail and report the holder
+	_, e = Acquire(dir, POLL_INTERVAL)
+	held, ok := e.(*HeldError)
+
+	if !ok {
+		t.Fatalf("Acquire() = %v, expected a HeldError", e)
+	}
+
+	if held.Holder == nil || held.Holder.Pid != os.Getpid() {
+		t.Errorf("HeldError.Holder = %v, expected pid %d", held.Holder, os.Getpid())
+	}
+
+	e = l.Release()
+
+	if e != nil {
+		t.Fatal("TestAcquire:", e)
+	}
+
+	l, e = Acquire(dir, 0)
+
+	if e != nil {
+		t.Fatalf("Acquire() after Release() = %v", e)
+	}
+
+	l.Release()
+}
@@ -8,24 +8,57 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/blacksfk/modtorio/api"
 	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/credentials"
+	"github.com/blacksfk/modtorio/factorio"
+	"github.com/blacksfk/modtorio/journal"
+	"github.com/blacksfk/modtorio/lock"
+	"github.com/blacksfk/modtorio/modlist"
+	"github.com/blacksfk/modtorio/output"
+	"github.com/blacksfk/modtorio/stage"
 )
 
 const (
-	CMD_SEARCH   = "search"
-	CMD_DOWNLOAD = "download"
-	CMD_UPDATE   = "update"
-	CMD_ENABLE   = "enable"
-	CMD_DISABLE  = "disable"
-	CMD_LIST     = "list"
-	CMD_HELP     = "help"
+	CMD_SEARCH       = "search"
+	CMD_DOWNLOAD     = "download"
+	CMD_INSTALL      = "install"
+	CMD_UPDATE       = "update"
+	CMD_ENABLE       = "enable"
+	CMD_DISABLE      = "disable"
+	CMD_REMOVE       = "remove"
+	CMD_CLEAN        = "clean"
+	CMD_ADOPT        = "adopt"
+	CMD_INSPECT      = "inspect"
+	CMD_VERIFY       = "verify"
+	CMD_REPAIR       = "repair"
+	CMD_DOCTOR       = "doctor"
+	CMD_LIST         = "list"
+	CMD_SNAPSHOT     = "snapshot"
+	CMD_SNAPSHOTS    = "snapshots"
+	CMD_RESTORE      = "restore"
+	CMD_HISTORY      = "history"
+	CMD_UNDO         = "undo"
+	CMD_GAME         = "game"
+	CMD_UPGRADE_PLAN = "upgrade-plan"
+	CMD_COMPAT       = "compat"
+	CMD_HELP         = "help"
+)
+
+const (
+	CACHE_DIR    = "modtorio"             // within the user's cache directory
+	LATEST_CACHE = "latest-releases.json" // the latest game versions, for offline use
 )
 
 type Command struct {
-	name string                               // command string
-	min  int                                  // minimum args for the command
-	fn   func(*ModtorioFlags, []string) error // function to handle the command
+	name    string                               // command string
+	min     int                                  // minimum args for the command
+	mutates bool                                 // command modifies the mods directory
+	fn      func(*ModtorioFlags, []string) error // function to handle the command
 }
 
 // compare a commandline string to the command's name
@@ -36,6 +69,10 @@ func (c Command) cmp(name string) bool {
 type ModtorioFlags struct {
 	dir      string
 	factorio *common.Semver
+	wait     time.Duration
+	force    bool
+	pidFile  string
+	output   string // text, json or yaml
 }
 
 // main function.
@@ -43,61 +80,191 @@ type ModtorioFlags struct {
 func main() {
 	// define flags
 	var strVer string
+	var timeout time.Duration
 	flags := &ModtorioFlags{}
 
 	flag.StringVar(&flags.dir, "dir", "./", "Working directory")
-	flag.StringVar(&strVer, "factorio", common.MATCH_ANY, "Factorio version")
+	flag.StringVar(&strVer, "factorio", common.MATCH_ANY, "Factorio version, stable or experimental")
+	flag.DurationVar(&flags.wait, "wait", 0, "How long to wait for another modtorio process to release the mods directory")
+	flag.BoolVar(&flags.force, "force", false, "Modify the mods directory even if factorio is running")
+	flag.StringVar(&flags.pidFile, "pid-file", "", "PID file of the factorio server using the mods directory")
+	flag.DurationVar(&timeout, "timeout", 0, "How long requests to factorio.com may take")
+	flag.StringVar(&flags.output, "output", output.TEXT, "Output format: text, json or yaml")
 
 	// parse the flags
 	flag.Parse()
 
+	if !output.Valid(flags.output) {
+		fmt.Printf("Unknown output format: %s\n", flags.output)
+		os.Exit(1)
+	}
+
+	// with structured output, everything printed for people goes to
+	// stderr so that stdout only holds the result
+	stdout := os.Stdout
+
+	if output.Structured(flags.output) {
+		os.Stdout = os.Stderr
+	}
+
+	cmd := ""
+	argv := flag.Args()
+	e := setup(flags, strVer, timeout)
+
+	if e == nil && len(argv) == 0 {
+		e = fmt.Errorf("No command specified")
+	}
+
+	if e == nil {
+		// remaining arguments are options or flags for the command
+		cmd = argv[0]
+		e = matchAndRun(cmd, flags, argv[1:])
+	}
+
+	if output.Structured(flags.output) {
+		if we := output.Write(stdout, flags.output, newEnvelope(cmd, e)); we != nil {
+			fmt.Println(we)
+		}
+	} else if e != nil {
+		fmt.Println(e)
+	}
+
+	if e != nil {
+		os.Exit(1)
+	}
+}
+
+// resolve the remaining flags and tidy up after interrupted runs
+func setup(flags *ModtorioFlags, strVer string, timeout time.Duration) error {
+	var e error
+	channel := ""
+
+	api.SetTimeout(timeout)
+
+	if api.IsChannel(strVer) {
+		channel = strVer
+		strVer, e = resolveChannel(channel)
+
+		if e != nil {
+			return fmt.Errorf("Factorio version flag: %v", e)
+		}
+	}
+
 	semver, e := common.NewSemver(strVer)
 
 	if e != nil {
-		fmt.Println("Factorio version flag:", e)
+		return fmt.Errorf("Factorio version flag: %v", e)
+	}
 
-		return
+	if channel != "" {
+		// releases only list the major and minor versions they support
+		semver.Patch = 0
 	}
 
 	flags.factorio = semver
 
-	// validate remaining arguments
-	argv := flag.Args()
-	argc := len(argv)
+	// flags given on the command line
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
 
-	if argc == 0 {
-		fmt.Println("No command specified")
+	detectInstall(flags, set["dir"], set["factorio"])
 
-		return
-	}
+	// remove temp files left behind by interrupted writes.
+	// best effort: the directories may not exist yet
+	common.RemoveStaleTemp(flags.dir)
+	common.RemoveStaleTemp(stage.Root(flags.dir))
+	common.RemoveStaleTemp(journal.Root(flags.dir))
+	common.RemoveStaleTemp(filepath.Dir(credentials.CACHE))
+	common.RemoveStaleTemp(factorio.GamesRoot())
 
-	var options []string
-	cmd := argv[0]
+	return nil
+}
 
-	if argc > 1 {
-		// options or flags for command
-		options = argv[1:]
+// get the latest version of the game on a release channel
+func resolveChannel(channel string) (string, error) {
+	cache := ""
+
+	if dir, e := os.UserCacheDir(); e == nil {
+		if e = os.MkdirAll(filepath.Join(dir, CACHE_DIR), common.STATE_DIR_MODE); e == nil {
+			cache = filepath.Join(dir, CACHE_DIR, LATEST_CACHE)
+		}
 	}
 
-	e = matchAndRun(cmd, flags, options)
+	version, cached, e := api.LatestVersion(channel, cache)
 
 	if e != nil {
-		fmt.Println(e)
+		return "", e
+	}
 
+	if cached {
+		fmt.Printf("Factorio %s: %s (cached, factorio.com could not be reached)\n", channel, version)
+	} else {
+		fmt.Printf("Factorio %s: %s\n", channel, version)
+	}
+
+	return version, nil
+}
+
+// fill in the mods directory and factorio version from the factorio
+// installation on this machine, unless they were given as flags. the working
+// directory is still used by default if it contains a mod list, and the
+// version defaults to the active headless server if there is no installation
+func detectInstall(flags *ModtorioFlags, dirSet, versionSet bool) {
+	if dirSet && versionSet {
 		return
 	}
+
+	installs := factorio.Detect()
+	var install *factorio.Install
+
+	if _, e := os.Stat(modlist.Path(flags.dir)); dirSet || e == nil {
+		install = factorio.FindMods(installs, flags.dir)
+	} else if len(installs) > 0 {
+		install = installs[0]
+		flags.dir = install.Mods
+		fmt.Fprintf(os.Stderr, "Using the mods directory %s (no %s in the working directory)\n", flags.dir, modlist.FILE_NAME)
+	}
+
+	if install == nil || install.Version == nil {
+		// default to the active headless server
+		install = factorio.ActiveGame()
+	}
+
+	if install != nil && install.Version != nil && !versionSet {
+		// releases only list the major and minor versions they support
+		flags.factorio = &common.Semver{Major: install.Version.Major, Minor: install.Version.Minor}
+		fmt.Fprintf(os.Stderr, "Detected factorio %v\n", install.Version)
+	}
 }
 
 func matchAndRun(name string, flags *ModtorioFlags, options []string) error {
 	optionCount := len(options)
 	commands := []Command{
-		{CMD_SEARCH, 1, search},
-		{CMD_DOWNLOAD, 1, download},
-		{CMD_UPDATE, 0, update},
-		{CMD_ENABLE, 1, enable},
-		{CMD_DISABLE, 1, disable},
-		{CMD_LIST, 0, list},
-		{CMD_HELP, 0, help},
+		{CMD_SEARCH, 1, false, search},
+		{CMD_DOWNLOAD, 1, true, download},
+		{CMD_INSTALL, 1, true, install},
+		{CMD_UPDATE, 0, true, update},
+		{CMD_ENABLE, 1, true, enable},
+		{CMD_DISABLE, 1, true, disable},
+		{CMD_REMOVE, 1, true, remove},
+		{CMD_CLEAN, 0, true, clean},
+		{CMD_ADOPT, 0, true, adopt},
+		{CMD_INSPECT, 1, false, inspect},
+		{CMD_VERIFY, 0, false, verify},
+		{CMD_REPAIR, 0, true, repair},
+		{CMD_DOCTOR, 0, false, doctor},
+		{CMD_LIST, 0, false, list},
+		{CMD_SNAPSHOT, 0, false, takeSnapshot},
+		{CMD_SNAPSHOTS, 0, false, listSnapshots},
+		{CMD_RESTORE, 1, true, restore},
+		{CMD_HISTORY, 0, false, history},
+		{CMD_UNDO, 0, true, undo},
+		{CMD_GAME, 1, false, game},
+		{CMD_UPGRADE_PLAN, 0, false, upgradePlan},
+		{CMD_COMPAT, 0, false, compat},
+		{CMD_HELP, 0, false, help},
 	}
 
 	// loop through all defined commands
@@ -106,7 +273,7 @@ func matchAndRun(name string, flags *ModtorioFlags, options []string) error {
 			// match found, compare minimum arguments
 			if optionCount >= cmd.min {
 				// minimum arguments found, call the handler
-				return cmd.fn(flags, options)
+				return run(cmd, flags, options)
 			} else {
 				// argument count does not meet the minimum
 				return fmt.Errorf("Not enough arguments for command: %s. Minimum: %d, Found: %d", cmd.name, cmd.min, optionCount)
@@ -117,3 +284,61 @@ func matchAndRun(name string, flags *ModtorioFlags, options []string) error {
 	// no match found
 	return fmt.Errorf("Invalid command: %s", name)
 }
+
+// run a command. commands that modify the mods directory
+// hold a lock on it for their duration, refuse to run
+// while factorio is using it, and are recorded in the journal
+func run(cmd Command, flags *ModtorioFlags, options []string) error {
+	if cmd.mutates {
+		l, e := lock.Acquire(flags.dir, flags.wait)
+
+		if e != nil {
+			return e
+		}
+
+		defer l.Release()
+
+		if !flags.force {
+			p, e := factorio.FindRunning(flags.dir, flags.pidFile)
+
+			if e != nil {
+				return e
+			}
+
+			if p != nil {
+				return fmt.Errorf("Factorio is running with the mods in %s (%v). Stop it first or use --force", flags.dir, p)
+			}
+		}
+
+		// undo reverts journal entries rather than creating them
+		if cmd.name != CMD_UNDO {
+			return runJournaled(cmd, flags, options)
+		}
+	}
+
+	return cmd.fn(flags, options)
+}
+
+// run a command, recording the changes it makes in the journal
+func runJournaled(cmd Command, flags *ModtorioFlags, options []string) error {
+	rec, e := journal.Begin(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	e = cmd.fn(flags, options)
+	entry, je := rec.End(os.Args[1:], e)
+
+	if je != nil {
+		// the command itself succeeded (or failed) regardless
+		fmt.Println("Failed to record the journal entry:", je)
+	}
+
+	if result == nil {
+		// commands without a result of their own report their changes
+		emit(newJournalEntry(entry))
+	}
+
+	return e
+}
@@ -0,0 +1,113 @@
+package modinfo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// kinds of dependency, determined by the prefix of the dependency string
+type Kind int
+
+const (
+	REQUIRED        Kind = iota // no prefix
+	OPTIONAL                    // ?
+	HIDDEN_OPTIONAL             // (?)
+	INCOMPATIBLE                // !
+	NO_LOAD_ORDER               // ~ required, but does not affect load order
+)
+
+var prefixes = []struct {
+	prefix string
+	kind   Kind
+}{
+	// (?) must be checked before ?
+	{"(?)", HIDDEN_OPTIONAL},
+	{"?", OPTIONAL},
+	{"!", INCOMPATIBLE},
+	{"~", NO_LOAD_ORDER},
+}
+
+// extract the name, and optional version constraint, of a dependency
+var depRe = regexp.MustCompile(`^(.+?)\s*(?:(>=|<=|=|>|<)\s*(\S+))?$`)
+
+// a dependency of a mod, eg. "? bobplates >= 1.1.0"
+type Dependency struct {
+	Kind        Kind
+	Name        string
+	Op, Version string // empty if there is no version constraint
+}
+
+func (d *Dependency) String() string {
+	b := strings.Builder{}
+
+	for _, p := range prefixes {
+		if p.kind == d.Kind {
+			b.WriteString(p.prefix)
+			b.WriteString(" ")
+		}
+	}
+
+	b.WriteString(d.Name)
+
+	if d.Op != "" {
+		b.WriteString(" ")
+		b.WriteString(d.Op)
+		b.WriteString(" ")
+		b.WriteString(d.Version)
+	}
+
+	return b.String()
+}
+
+// check if the dependency must be enabled for the mod to load
+func (d *Dependency) Required() bool {
+	return d.Kind == REQUIRED || d.Kind == NO_LOAD_ORDER
+}
+
+// Parse a dependency string from info.json.
+func ParseDependency(s string) (*Dependency, error) {
+	s = strings.TrimSpace(s)
+	dep := &Dependency{Kind: REQUIRED}
+
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p.prefix) {
+			dep.Kind = p.kind
+			s = strings.TrimSpace(strings.TrimPrefix(s, p.prefix))
+
+			break
+		}
+	}
+
+	matches := depRe.FindStringSubmatch(s)
+
+	if matches == nil {
+		return nil, fmt.Errorf("Invalid dependency: %s", s)
+	}
+
+	// match found:
+	// [0]: full match
+	// [1]: mod name
+	// [2]: operator (optional)
+	// [3]: version (optional)
+	dep.Name = matches[1]
+	dep.Op = matches[2]
+	dep.Version = matches[3]
+
+	return dep, nil
+}
+
+func (k Kind) String() string {
+	switch k {
+	case OPTIONAL:
+		return "optional"
+	case HIDDEN_OPTIONAL:
+		return "hidden optional"
+	case INCOMPATIBLE:
+		return "incompatible"
+	case NO_LOAD_ORDER:
+		return "required, no load order"
+	default:
+		return "required"
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Package to read the metadata (info.json) of mod archives.
+*/
+package modinfo
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	INFO_FILE = "info.json"
+)
+
+// default dependencies if info.json does not list any
+var defaultDependencies = []string{"base"}
+
+// contents of a mod's info.json
+type Info struct {
+	Name, Version, Title  string
+	Author, Contact       string
+	Homepage, Description string
+	Factorio_version      string
+	Dependencies          []string
+}
+
+// parse the contents of an info.json file
+func Parse(bytes []byte) (*Info, error) {
+	info := &Info{}
+	e := json.Unmarshal(bytes, info)
+
+	if e != nil {
+		return nil, fmt.Errorf("Invalid %s: %v", INFO_FILE, e)
+	}
+
+	if info.Name == "" || info.Version == "" {
+		return nil, fmt.Errorf("Invalid %s: name and version are required", INFO_FILE)
+	}
+
+	if info.Dependencies == nil {
+		info.Dependencies = defaultDependencies
+	}
+
+	return info, nil
+}
+
+// Read info.json from a mod archive on disk.
+func ReadZip(path string) (*Info, error) {
+	z, e := zip.OpenReader(path)
+
+	if e != nil {
+		return nil, e
+	}
+
+	defer z.Close()
+
+	return Read(&z.Reader)
+}
+
+// Read info.json from an opened mod archive. info.json must be in the
+// archive's top level folder.
+func Read(z *zip.Reader) (*Info, error) {
+	file := FindInfo(z)
+
+	if file == nil {
+		return nil, fmt.Errorf("No %s found in the archive's top level folder", INFO_FILE)
+	}
+
+	r, e := file.Open()
+
+	if e != nil {
+		return nil, e
+	}
+
+	defer r.Close()
+	bytes, e := io.ReadAll(r)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return Parse(bytes)
+}
+
+// Find info.json within an archive's top level folder. Returns nil if
+// there is no such file.
+func FindInfo(z *zip.Reader) *zip.File {
+	for _, file := range z.File {
+		parts := strings.Split(file.Name, "/")
+
+		if len(parts) == 2 && parts[1] == INFO_FILE {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// Parse all of the mod's dependencies.
+func (info *Info) Deps() ([]*Dependency, error) {
+	var deps []*Dependency
+
+	for _, s := range info.Dependencies {
+		dep, e := ParseDependency(s)
+
+		if e != nil {
+			return nil, e
+		}
+
+		deps = append(deps, dep)
+	}
+
+	return deps, nil
+}
+
+// Get the names of the mods this mod requires to load.
+func (info *Info) Required() ([]string, error) {
+	deps, e := info.Deps()
+
+	if e != nil {
+		return nil, e
+	}
+
+	var names []string
+
+	for _, dep := range deps {
+		if dep.Required() {
+			names = append(names, dep.Name)
+		}
+	}
+
+	return names, nil
+}
@@ -0,0 +1,82 @@
+package modinfo
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestParseDependency(t *testing.T) {
+	cases := []struct {
+		s                 string
+		kind              Kind
+		name, op, version string
+	}{
+		{"base >= 1.1", REQUIRED, "base", ">=", "1.1"},
+		{"? bobplates", OPTIONAL, "bobplates", "", ""},
+		{"(?) angelsrefining>0.1.0", HIDDEN_OPTIONAL, "angelsrefining", ">", "0.1.0"},
+		{"!bobinserters", INCOMPATIBLE, "bobinserters", "", ""},
+		{"~ flib = 0.12.0", NO_LOAD_ORDER, "flib", "=", "0.12.0"},
+		{"Mod with spaces", REQUIRED, "Mod with spaces", "", ""},
+	}
+
+	for _, c := range cases {
+		dep, e := ParseDependency(c.s)
+
+		if e != nil {
+			t.Errorf("ParseDependency(%s): %s", c.s, e)
+			continue
+		}
+
+		if dep.Kind != c.kind || dep.Name != c.name || dep.Op != c.op || dep.Version != c.version {
+			t.Errorf("ParseDependency(%s) = %+v", c.s, dep)
+		}
+	}
+
+	if _, e := ParseDependency(""); e == nil {
+		t.Error("ParseDependency() of an empty string should fail")
+	}
+}
+
+func TestRead(t *testing.T) {
+	b := bytes.Buffer{}
+	w := zip.NewWriter(&b)
+	files := map[string]string{
+		"mod_1.0.0/info.json":      `{"name": "mod", "version": "1.0.0", "dependencies": ["base", "? other", "lib"]}`,
+		"mod_1.0.0/data.lua":       "",
+		"mod_1.0.0/test/info.json": "not this one",
+	}
+
+	for name, data := range files {
+		f, e := w.Create(name)
+
+		if e != nil {
+			t.Fatal("TestRead:", e)
+		}
+
+		f.Write([]byte(data))
+	}
+
+	w.Close()
+	z, e := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+
+	if e != nil {
+		t.Fatal("TestRead:", e)
+	}
+
+	info, e := Read(z)
+
+	if e != nil {
+		t.Fatal("TestRead:", e)
+	}
+
+	required, e := info.Required()
+
+	if e != nil {
+		t.Fatal("TestRead:", e)
+	}
+
+	if info.Name != "mod" || len(required) != 2 || required[1] != "lib" {
+		t.Errorf("Read() = %+v, required: %v", info, required)
+	}
+}
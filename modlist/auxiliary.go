@@ -2,17 +2,17 @@ package modlist
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 )
 
-// add mods to the list and enable them
-func Add(dir string, names ...string) error {
-	list, e := Read(dir)
-
-	if e != nil {
-		return e
-	}
+const (
+	DLC_ENABLE_WARNING  = "Warning: %s is part of the Space Age expansion. It requires owning the expansion, and saves made with it enabled cannot be loaded without it.\n"
+	DLC_DISABLE_WARNING = "Warning: %s is part of the Space Age expansion. Saves made with it enabled cannot be loaded while it is disabled.\n"
+)
 
+// add mods to the list and enable them, without writing the list
+func (list *ModList) Add(names ...string) {
 	for _, name := range names {
 		found := false
 
@@ -27,42 +27,118 @@ func Add(dir string, names ...string) error {
 
 		if !found {
 			// mod does not exist, add and enable it
-			newMod := &Mod{name, true, nil}
+			newMod := &Mod{Name: name, Enabled: true}
 			list.Mods = append(list.Mods, newMod)
 		}
 	}
+}
+
+// remove mods from the list, without writing the list
+func (list *ModList) Remove(names ...string) {
+	var mods []*Mod
+
+	for _, mod := range list.Mods {
+		found := false
+
+		for _, name := range names {
+			if mod.Name == name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			mods = append(mods, mod)
+		}
+	}
 
-	return list.Write(dir)
+	list.Mods = mods
 }
 
-// set the "enabled" status of mods
-func SetStatus(dir string, enabled bool, names []string) error {
-	list, e := Read(dir)
+// how patterns are matched against mod names (see Match)
+type MatchMode int
+
+const (
+	MATCH_EXACT MatchMode = iota // the pattern is the mod name
+	MATCH_REGEX                  // the pattern is an (unanchored) regular expression
+	MATCH_GLOB                   // the pattern is a shell glob (eg. bob*)
+)
+
+// find the mods matching any of the patterns. DLC components may also be
+// matched, but the base mod never is. returns the matched mods (in list order,
+// without duplicates) and every pattern that matched nothing
+func (list *ModList) Match(mode MatchMode, patterns []string) ([]*Mod, []string, error) {
+	var candidates []*Mod
 
-	if e != nil {
-		return e
+	for _, mod := range list.Builtin {
+		if IsDLC(mod.Name) {
+			candidates = append(candidates, mod)
+		}
 	}
 
-	for _, name := range names {
-		re, e := regexp.Compile(name)
+	candidates = append(candidates, list.Mods...)
+	matched := map[*Mod]bool{}
+	var unmatched []string
+
+	for _, pattern := range patterns {
+		match, e := matcher(mode, pattern)
 
 		if e != nil {
-			return e
+			return nil, nil, e
 		}
 
 		found := false
 
-		for _, mod := range list.Mods {
-			if re.MatchString(mod.Name) {
-				mod.Enabled = enabled
+		for _, mod := range candidates {
+			if match(mod.Name) {
+				matched[mod] = true
 				found = true
 			}
 		}
 
 		if !found {
-			return fmt.Errorf("%s not found in the mod list", name)
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	var mods []*Mod
+
+	for _, mod := range candidates {
+		if matched[mod] {
+			mods = append(mods, mod)
 		}
 	}
 
-	return list.Write(dir)
+	return mods, unmatched, nil
+}
+
+// create a function matching a mod name against a pattern
+func matcher(mode MatchMode, pattern string) (func(string) bool, error) {
+	switch mode {
+	case MATCH_REGEX:
+		re, e := regexp.Compile(pattern)
+
+		if e != nil {
+			return nil, e
+		}
+
+		return re.MatchString, nil
+	case MATCH_GLOB:
+		// check the pattern is well formed
+		_, e := path.Match(pattern, "")
+
+		if e != nil {
+			return nil, fmt.Errorf("Invalid glob %s: %v", pattern, e)
+		}
+
+		return func(name string) bool {
+			ok, _ := path.Match(pattern, name)
+
+			return ok
+		}, nil
+	default:
+		return func(name string) bool {
+			return name == pattern
+		}, nil
+	}
 }
@@ -0,0 +1,56 @@
+package modlist
+
+import (
+	"github.com/blacksfk/modtorio/common"
+)
+
+// a mod shipped with the game rather than downloaded from the mod portal
+type builtin struct {
+	name  string
+	since *common.Semver // first factorio version shipping the mod
+	dlc   bool           // part of the Space Age expansion
+}
+
+var builtins = []builtin{
+	{BASE, &common.Semver{Major: 0, Minor: 0, Patch: 0}, false},
+	{"elevated-rails", &common.Semver{Major: 2, Minor: 0, Patch: 0}, true},
+	{"quality", &common.Semver{Major: 2, Minor: 0, Patch: 0}, true},
+	{"space-age", &common.Semver{Major: 2, Minor: 0, Patch: 0}, true},
+}
+
+// get the names of the mods built in to a factorio version.
+// all known built-in mods are returned for common.MATCH_ANY
+func Builtins(factorio *common.Semver) []string {
+	var names []string
+
+	for _, b := range builtins {
+		if factorio.Cmp(b.since) >= 0 {
+			names = append(names, b.name)
+		}
+	}
+
+	return names
+}
+
+// check if a mod is built in to a factorio version
+func IsBuiltin(name string, factorio *common.Semver) bool {
+	for _, b := range builtins {
+		if b.name == name {
+			return factorio.Cmp(b.since) >= 0
+		}
+	}
+
+	return false
+}
+
+// check if a mod is a DLC component. DLC mods can be enabled and disabled,
+// but require the expansion to be owned and affect save compatibility
+func IsDLC(name string) bool {
+	for _, b := range builtins {
+		if b.name == name {
+			return b.dlc
+		}
+	}
+
+	return false
+}
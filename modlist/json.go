@@ -0,0 +1,77 @@
+package modlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// marshal v (a struct) and append the unknown fields after its own,
+// sorted by key so that the output is stable between runs
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	known, e := json.Marshal(v)
+
+	if e != nil || len(extra) == 0 {
+		return known, e
+	}
+
+	keys := make([]string, 0, len(extra))
+
+	for key := range extra {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	// strip the closing brace and write the unknown fields in its place
+	b := bytes.Buffer{}
+	b.Write(known[:len(known)-1])
+
+	for i, key := range keys {
+		if i > 0 || len(known) > 2 {
+			// only write a comma if something precedes this field
+			b.WriteByte(',')
+		}
+
+		name, e := json.Marshal(key)
+
+		if e != nil {
+			return nil, e
+		}
+
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(extra[key])
+	}
+
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+// unmarshal data into v (a struct) and return every field that is not one
+// of the known keys. returns nil if there are no unknown fields
+func unmarshalWithExtra(data []byte, v interface{}, known ...string) (map[string]json.RawMessage, error) {
+	e := json.Unmarshal(data, v)
+
+	if e != nil {
+		return nil, e
+	}
+
+	fields := map[string]json.RawMessage{}
+	e = json.Unmarshal(data, &fields)
+
+	if e != nil {
+		return nil, e
+	}
+
+	for _, key := range known {
+		delete(fields, key)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return fields, nil
+}
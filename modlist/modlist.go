@@ -6,93 +6,175 @@ package modlist
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/blacksfk/modtorio/common"
 )
 
 const (
-	MODE       = 0644
-	VERSION_RE = `(\d(?:\.\d+)+)`
-	FILE_NAME  = "mod-list.json"
+	MODE        = 0644
+	VERSION_RE  = `(\d+(?:\.\d+)+)`
+	ARCHIVE_EXT = ".zip"
+	FILE_NAME   = "mod-list.json"
+	INDENT      = "  " // factorio indents mod-list.json with two spaces
 )
 
 type ModList struct {
 	Mods []*Mod `json:"mods"`
-}
 
-// write the mod list in the specified directory
-func (list *ModList) Write(dir string) error {
-	path := genPath(dir)
+	// mods built in to the game (base and DLC) as they were read from
+	// the file. kept apart from Mods as they are not on the mod portal
+	Builtin []*Mod `json:"-"`
 
-	// base mod should not be in the list, so append it
-	list.Mods = append(list.Mods, base)
+	// top level fields other than "mods", preserved as-is
+	extra map[string]json.RawMessage
+}
 
-	bytes, e := json.Marshal(list)
+// write the mod list in the specified directory.
+// the file is written the same way factorio writes it: indented,
+// with base first and the remaining mods in alphabetical order
+func (list *ModList) Write(dir string) error {
+	path := Path(dir)
+	bytes, e := list.Marshal()
 
 	if e != nil {
 		return e
 	}
 
-	return os.WriteFile(path, bytes, MODE)
+	return common.WriteFileAtomic(path, bytes, MODE)
+}
+
+// encode the mod list as it is written to mod-list.json
+func (list *ModList) Marshal() ([]byte, error) {
+	b := &Mod{Name: BASE, Enabled: true}
+	mods := make([]*Mod, 0, len(list.Mods)+len(list.Builtin))
+
+	for _, mod := range list.Builtin {
+		if mod.Name == BASE {
+			// keep the base mod as it was read
+			b = mod
+		} else {
+			mods = append(mods, mod)
+		}
+	}
+
+	// copy the mods so sorting does not reorder the caller's list
+	mods = append(mods, list.Mods...)
+	sortMods(mods)
+
+	out := &ModList{Mods: append([]*Mod{b}, mods...), extra: list.extra}
+
+	return json.MarshalIndent(out, "", INDENT)
+}
+
+// encode the mods first, followed by any unknown top level fields
+func (list *ModList) MarshalJSON() ([]byte, error) {
+	type known ModList
+
+	return marshalWithExtra((*known)(list), list.extra)
+}
+
+// decode the mods and keep any unknown top level fields
+func (list *ModList) UnmarshalJSON(bytes []byte) error {
+	type known ModList
+	var e error
+
+	list.extra, e = unmarshalWithExtra(bytes, (*known)(list), "mods")
+
+	return e
 }
 
 // populate the archive file data for all mods in this list.
+// if a mod has several archives the newest version is used.
 // does not return an error if no match for a mod is found.
 func (list *ModList) FindArchives(dir string) error {
-	files, e := os.ReadDir(dir)
+	archives, _, e := ScanArchives(dir)
 
 	if e != nil {
 		return e
 	}
 
 	for _, mod := range list.Mods {
-		b := strings.Builder{}
-		b.WriteString(mod.Name)   // match the mod name exactly
-		b.WriteString("_")        // underscore between name and version
-		b.WriteString(VERSION_RE) // match any version
+		mod.Archive = nil
+
+		for _, archive := range archives {
+			if archive.Mod == mod.Name && (mod.Archive == nil || archive.Semver.Cmp(mod.Archive.Semver) > 0) {
+				mod.Archive = archive
+			}
+		}
+	}
+
+	return nil
+}
+
+// find every mod archive (<mod_name>_<mod_version>.zip) in a directory.
+// also returns the names of .zip files that are not named like a mod archive
+func ScanArchives(dir string) ([]*Archive, []string, error) {
+	files, e := os.ReadDir(dir)
+
+	if e != nil {
+		return nil, nil, e
+	}
+
+	var archives []*Archive
+	var unparseable []string
+
+	for _, file := range files {
+		name := file.Name()
+
+		if !file.Type().IsRegular() || !strings.HasSuffix(name, ARCHIVE_EXT) {
+			continue
+		}
+
+		matches := archiveRe.FindStringSubmatch(name)
+
+		if matches == nil {
+			unparseable = append(unparseable, name)
+			continue
+		}
 
-		re, e := regexp.Compile(b.String())
+		// match found:
+		// [0]: full match (<mod_name>_<mod_version>.zip)
+		// [1]: mod name
+		// [2]: version (<mod_version> eg. 0.17.3333)
+		archive, e := NewArchive(name, matches[1], matches[2])
 
 		if e != nil {
-			// regexp compilation failure
-			return e
+			// something went wrong with semantic version extraction,
+			// no reason to stop processing
+			unparseable = append(unparseable, name)
+			continue
 		}
 
-		length := len(files)
-
-		for i := 0; i < length; i++ {
-			matches := re.FindStringSubmatch(files[i].Name())
-
-			if matches != nil {
-				// match found:
-				// [0]: full match (<mod_name>_<mod_version>)
-				// [1]: version sub-group (<mod_version> eg. 0.17.3333)
-				mod.Archive, e = NewArchive(matches[0], matches[1])
-
-				if e != nil {
-					// something went wrong with semantic version extraction,
-					// no reason to stop processing
-					fmt.Println(e)
-				}
-
-				// remove the file from the list (no longer needed for comparison)
-				if i == 0 {
-					// file is at the beginning
-					files = files[1:]
-				} else if i == length-1 {
-					// file is at the end
-					files = files[:i]
-				} else {
-					// file is in the middle somewhere
-					files = append(files[:i], files[i+1:]...)
-				}
-
-				break
-			}
+		archives = append(archives, archive)
+	}
+
+	return archives, unparseable, nil
+}
+
+// get a mod (or DLC component) by name. returns nil if it is not in the list
+func (list *ModList) Get(name string) *Mod {
+	for _, mod := range list.Mods {
+		if mod.Name == name {
+			return mod
+		}
+	}
+
+	if IsDLC(name) {
+		return list.GetBuiltin(name)
+	}
+
+	return nil
+}
+
+// get a built-in mod by name. returns nil if it was not in the file
+func (list *ModList) GetBuiltin(name string) *Mod {
+	for _, mod := range list.Builtin {
+		if mod.Name == name {
+			return mod
 		}
 	}
 
@@ -116,33 +198,72 @@ type Mod struct {
 	// the archive data should not be written to mod-list.json,
 	// so keep it hidden with tag: "-"
 	Archive *Archive `json:"-"`
+
+	// fields other than name and enabled, preserved as-is
+	extra map[string]json.RawMessage
 }
 
+// encode name and enabled first, followed by any unknown fields
+func (mod *Mod) MarshalJSON() ([]byte, error) {
+	type known Mod
+
+	return marshalWithExtra((*known)(mod), mod.extra)
+}
+
+// decode name and enabled and keep any unknown fields
+func (mod *Mod) UnmarshalJSON(bytes []byte) error {
+	type known Mod
+	var e error
+
+	mod.extra, e = unmarshalWithExtra(bytes, (*known)(mod), "name", "enabled")
+
+	return e
+}
+
+// get the version the mod is pinned to in mod-list.json. returns an empty
+// string if it is not pinned
+func (mod *Mod) PinnedVersion() string {
+	var version string
+
+	if raw, ok := mod.extra["version"]; ok {
+		json.Unmarshal(raw, &version)
+	}
+
+	return version
+}
+
+// match a mod archive's file name exactly: <mod_name>_<mod_version>.zip
+var archiveRe = regexp.MustCompile(`^(.+)_` + VERSION_RE + regexp.QuoteMeta(ARCHIVE_EXT) + `$`)
+
 type Archive struct {
-	Name, Version string
+	File          string // file name within the mods directory
+	Mod           string // name of the mod
+	Name, Version string // name is <mod_name>_<mod_version>
 	Semver        *common.Semver
 }
 
 // Extract the semantic version from `version` and create
 // a new archive. Returns an error if semantic version extraction
 // failed.
-func NewArchive(name, version string) (*Archive, error) {
+func NewArchive(file, mod, version string) (*Archive, error) {
 	semver, e := common.NewSemver(version)
 
 	if e != nil {
 		return nil, e
 	}
 
-	return &Archive{name, version, semver}, nil
+	return &Archive{file, mod, mod + "_" + version, version, semver}, nil
 }
 
 // base mod should always be present in the file,
 // but does not have an archive. so it is removed before
 // a read is returned, and is added during a write
-var base *Mod = &Mod{"base", true, nil}
+const BASE = "base"
 
-func Read(dir string) (*ModList, error) {
-	path := genPath(dir)
+// read the mod list in the specified directory. mods built in to the
+// given factorio version (see IsBuiltin) are moved to list.Builtin
+func Read(dir string, factorio *common.Semver) (*ModList, error) {
+	path := Path(dir)
 	bytes, e := os.ReadFile(path)
 
 	if e != nil {
@@ -156,40 +277,52 @@ func Read(dir string) (*ModList, error) {
 	}
 
 	// file exists and is readable
+	return Parse(bytes, factorio)
+}
+
+// parse the contents of a mod list file. see Read
+func Parse(bytes []byte, factorio *common.Semver) (*ModList, error) {
 	list := &ModList{}
-	e = json.Unmarshal(bytes, list)
+	e := json.Unmarshal(bytes, list)
 
 	if e != nil {
 		return nil, e
 	}
 
-	length := len(list.Mods)
-
-	// loop through the mod list and remove the base mod
-	// so as not to interfere with downloading, updating etc
-	for i := 0; i < length; i++ {
-		if list.Mods[i].Name == base.Name {
-			// base mod found
-			if i == 0 {
-				// base mod is at the beginning
-				list.Mods = list.Mods[1:]
-			} else if i == length-1 {
-				// base mod is at the end
-				list.Mods = list.Mods[:i]
-			} else {
-				// base mod is in the middle
-				list.Mods = append(list.Mods[:i], list.Mods[i+1:]...)
-			}
+	var mods []*Mod
 
-			break
+	// remove the base mod (and any DLC) from the list so as not to
+	// interfere with downloading, updating etc.
+	// they are kept aside so that they are written back unchanged
+	for _, mod := range list.Mods {
+		if IsBuiltin(mod.Name, factorio) {
+			list.Builtin = append(list.Builtin, mod)
+		} else {
+			mods = append(mods, mod)
 		}
 	}
 
-	// base mod removed (or new, empty list)
+	list.Mods = mods
+
+	// built-in mods removed (or new, empty list)
 	return list, nil
 }
 
-func genPath(dir string) string {
+// sort mods alphabetically (case-insensitive), as factorio does
+func sortMods(mods []*Mod) {
+	sort.SliceStable(mods, func(i, j int) bool {
+		a, b := strings.ToLower(mods[i].Name), strings.ToLower(mods[j].Name)
+
+		if a == b {
+			return mods[i].Name < mods[j].Name
+		}
+
+		return a < b
+	})
+}
+
+// get the path of the mod list file in a directory
+func Path(dir string) string {
 	if dir[len(dir)-1] != '/' {
 		// append a slash
 		dir += "/"
@@ -0,0 +1,241 @@
+package modlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blacksfk/modtorio/common"
+)
+
+var matchAny, _ = common.NewSemver(common.MATCH_ANY)
+
+func TestReadWriteRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	in := `{"mods":[{"name":"zmod","enabled":false},{"name":"base","enabled":true,"note":1},` +
+		`{"name":"Amod","enabled":true,"version":"1.2.3"}],"extra":{"a":true}}`
+	expected := `{
+  "mods": [
+    {
+      "name": "base",
+      "enabled": true,
+      "note": 1
+    },
+    {
+      "name": "Amod",
+      "enabled": true,
+      "version": "1.2.3"
+    },
+    {
+      "name": "zmod",
+      "enabled": false
+    }
+  ],
+  "extra": {
+    "a": true
+  }
+}`
+
+	e := os.WriteFile(Path(dir), []byte(in), MODE)
+
+	if e != nil {
+		t.Fatal("TestReadWriteRoundTrip:", e)
+	}
+
+	list, e := Read(dir, matchAny)
+
+	if e != nil {
+		t.Fatal("TestReadWriteRoundTrip:", e)
+	}
+
+	if count := len(list.Mods); count != 2 {
+		t.Fatalf("Read() returned %d mods, expected: 2", count)
+	}
+
+	if v := list.Get("Amod").PinnedVersion(); v != "1.2.3" {
+		t.Errorf("PinnedVersion() = %q, expected: 1.2.3", v)
+	}
+
+	if v := list.Get("zmod").PinnedVersion(); v != "" {
+		t.Errorf("PinnedVersion() of an unpinned mod = %q, expected an empty string", v)
+	}
+
+	e = list.Write(dir)
+
+	if e != nil {
+		t.Fatal("TestReadWriteRoundTrip:", e)
+	}
+
+	actual, e := os.ReadFile(Path(dir))
+
+	if e != nil {
+		t.Fatal("TestReadWriteRoundTrip:", e)
+	}
+
+	if string(actual) != expected {
+		t.Errorf("Write() =\n%s\nexpected:\n%s", actual, expected)
+	}
+
+	// writing must not reorder or modify the caller's list
+	if list.Mods[0].Name != "zmod" || len(list.Mods) != 2 {
+		t.Errorf("Write() modified the mod list: %v", list.GetAllModNames())
+	}
+}
+
+func TestWriteNewList(t *testing.T) {
+	dir := t.TempDir()
+	list, e := Read(dir, matchAny)
+
+	if e != nil {
+		t.Fatal("TestWriteNewList:", e)
+	}
+
+	list.Mods = append(list.Mods, &Mod{Name: "helicopters", Enabled: true})
+	bytes, e := list.Marshal()
+
+	if e != nil {
+		t.Fatal("TestWriteNewList:", e)
+	}
+
+	expected := `{
+  "mods": [
+    {
+      "name": "base",
+      "enabled": true
+    },
+    {
+      "name": "helicopters",
+      "enabled": true
+    }
+  ]
+}`
+
+	if string(bytes) != expected {
+		t.Errorf("Marshal() =\n%s\nexpected:\n%s", bytes, expected)
+	}
+}
+
+func TestReadBuiltin(t *testing.T) {
+	in := `{"mods":[{"name":"base","enabled":true},{"name":"space-age","enabled":true},` +
+		`{"name":"quality","enabled":false},{"name":"helicopters","enabled":true}]}`
+
+	cases := []struct {
+		version       string
+		mods, builtin int
+	}{
+		{common.MATCH_ANY, 1, 3},
+		{"2.0", 1, 3},
+		{"1.1", 3, 1},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		e := os.WriteFile(Path(dir), []byte(in), MODE)
+
+		if e != nil {
+			t.Fatal("TestReadBuiltin:", e)
+		}
+
+		factorio, e := common.NewSemver(c.version)
+
+		if e != nil {
+			t.Fatal("TestReadBuiltin:", e)
+		}
+
+		list, e := Read(dir, factorio)
+
+		if e != nil {
+			t.Fatal("TestReadBuiltin:", e)
+		}
+
+		if len(list.Mods) != c.mods || len(list.Builtin) != c.builtin {
+			t.Errorf("Read(%s) = %d mods, %d built-in, expected: %d mods, %d built-in", c.version, len(list.Mods), len(list.Builtin), c.mods, c.builtin)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	list := &ModList{
+		Mods:    []*Mod{{Name: "bob"}, {Name: "bobinserters"}, {Name: "angelsrefining"}},
+		Builtin: []*Mod{{Name: BASE}, {Name: "space-age"}},
+	}
+
+	cases := []struct {
+		mode      MatchMode
+		patterns  []string
+		matched   int
+		unmatched int
+	}{
+		{MATCH_EXACT, []string{"bob"}, 1, 0},
+		{MATCH_EXACT, []string{"bob", "x", "y"}, 1, 2},
+		{MATCH_GLOB, []string{"bob*"}, 2, 0},
+		{MATCH_REGEX, []string{"bob"}, 2, 0},
+		{MATCH_REGEX, []string{".*"}, 4, 0}, // never base
+		{MATCH_EXACT, []string{BASE}, 0, 1},
+		{MATCH_EXACT, []string{"space-age", "space-age"}, 1, 0},
+	}
+
+	for _, c := range cases {
+		matched, unmatched, e := list.Match(c.mode, c.patterns)
+
+		if e != nil {
+			t.Errorf("Match(%d, %v): %s", c.mode, c.patterns, e)
+			continue
+		}
+
+		if len(matched) != c.matched || len(unmatched) != c.unmatched {
+			t.Errorf("Match(%d, %v) = %d matched, %d unmatched, expected: %d, %d", c.mode, c.patterns, len(matched), len(unmatched), c.matched, c.unmatched)
+		}
+	}
+}
+
+func TestFindArchives(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"bob_1.0.0.zip",
+		"bob_1.2.0.zip",
+		"bobinserters_1.1.0.zip",
+		"my_mod_10.0.3.zip",
+		"notamod.zip",
+		"bob_1.3.0.zip.tmp",
+	}
+
+	for _, file := range files {
+		e := os.WriteFile(filepath.Join(dir, file), nil, MODE)
+
+		if e != nil {
+			t.Fatal("TestFindArchives:", e)
+		}
+	}
+
+	archives, unparseable, e := ScanArchives(dir)
+
+	if e != nil {
+		t.Fatal("TestFindArchives:", e)
+	}
+
+	if len(archives) != 4 || len(unparseable) != 1 || unparseable[0] != "notamod.zip" {
+		t.Errorf("ScanArchives() = %d archives, unparseable: %v", len(archives), unparseable)
+	}
+
+	list := &ModList{Mods: []*Mod{{Name: "bob"}, {Name: "my_mod"}, {Name: "missing"}}}
+	e = list.FindArchives(dir)
+
+	if e != nil {
+		t.Fatal("TestFindArchives:", e)
+	}
+
+	expected := []string{"bob_1.2.0.zip", "my_mod_10.0.3.zip", ""}
+
+	for i, mod := range list.Mods {
+		actual := ""
+
+		if mod.Archive != nil {
+			actual = mod.Archive.File
+		}
+
+		if actual != expected[i] {
+			t.Errorf("FindArchives(): %s = %q, expected: %q", mod.Name, actual, expected[i])
+		}
+	}
+}
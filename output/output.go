@@ -0,0 +1,50 @@
+/*
+Package to write command results in machine-readable formats.
+*/
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	TEXT = "text" // human-readable output printed by each command
+	JSON = "json"
+	YAML = "yaml"
+)
+
+// Check if a format is supported.
+func Valid(format string) bool {
+	return format == TEXT || format == JSON || format == YAML
+}
+
+// Check if a format is machine-readable.
+func Structured(format string) bool {
+	return format == JSON || format == YAML
+}
+
+// Write v to w in a structured format. v is encoded as it would be by
+// encoding/json, so json struct tags apply to both formats.
+func Write(w io.Writer, format string, v interface{}) error {
+	var bytes []byte
+	var e error
+
+	switch format {
+	case JSON:
+		bytes, e = json.MarshalIndent(v, "", "  ")
+	case YAML:
+		bytes, e = MarshalYAML(v)
+	default:
+		return fmt.Errorf("Unknown output format: %s", format)
+	}
+
+	if e != nil {
+		return e
+	}
+
+	_, e = fmt.Fprintln(w, string(bytes))
+
+	return e
+}
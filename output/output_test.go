@@ -0,0 +1,64 @@
+package output
+
+import (
+	"testing"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	type release struct {
+		Version string  `json:"version"`
+		Sha1    *string `json:"sha1"`
+	}
+
+	value := struct {
+		Name     string            `json:"name"`
+		Enabled  bool              `json:"enabled"`
+		Count    int               `json:"count"`
+		Tags     []string          `json:"tags"`
+		Empty    []string          `json:"empty"`
+		Releases []release         `json:"releases"`
+		Extra    map[string]string `json:"extra"`
+	}{
+		Name:     "bob's mod",
+		Enabled:  true,
+		Count:    3,
+		Tags:     []string{"logistics", "yes", "1.1"},
+		Empty:    []string{},
+		Releases: []release{{"1.0.0", nil}, {"2.0.0", nil}},
+		Extra:    map[string]string{"2.0": "line\nbreak"},
+	}
+
+	expected := `name: "bob's mod"
+enabled: true
+count: 3
+tags:
+  - logistics
+  - "yes"
+  - "1.1"
+empty: []
+releases:
+  - version: "1.0.0"
+    sha1: null
+  - version: "2.0.0"
+    sha1: null
+extra:
+  "2.0": "line\nbreak"`
+
+	bytes, e := MarshalYAML(value)
+
+	if e != nil {
+		t.Fatal("TestMarshalYAML:", e)
+	}
+
+	if string(bytes) != expected {
+		t.Errorf("MarshalYAML() =\n%s\nexpected:\n%s", bytes, expected)
+	}
+
+	for v, expected := range map[interface{}]string{"text": "text", 1.5: "1.5", nil: "null"} {
+		bytes, e := MarshalYAML(v)
+
+		if e != nil || string(bytes) != expected {
+			t.Errorf("MarshalYAML(%v) = %s, %v, expected %s", v, bytes, e, expected)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	YAML_INDENT = "  "
+)
+
+// strings that can be written without quotes: starting with a letter and
+// without characters that mean something to YAML
+var plainRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_./-]*$`)
+
+// plain strings YAML would read as something other than a string
+var keywords = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true,
+}
+
+// a JSON object with its keys in order
+type object []member
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+// Encode v as a YAML document. v is first encoded as JSON, so the YAML has
+// the same structure, keys (in the same order) and values.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	data, e := json.Marshal(v)
+
+	if e != nil {
+		return nil, e
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, e := decode(dec)
+
+	if e != nil {
+		return nil, e
+	}
+
+	b := &strings.Builder{}
+
+	if isCollection(value) {
+		writeYAML(b, value, "")
+	} else {
+		b.WriteString(scalar(value))
+	}
+
+	return []byte(strings.TrimSuffix(b.String(), "\n")), nil
+}
+
+// decode the next JSON value, keeping the order of object keys
+func decode(dec *json.Decoder) (interface{}, error) {
+	token, e := dec.Token()
+
+	if e != nil {
+		return nil, e
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := object{}
+
+		for dec.More() {
+			key, e := dec.Token()
+
+			if e != nil {
+				return nil, e
+			}
+
+			value, e := decode(dec)
+
+			if e != nil {
+				return nil, e
+			}
+
+			obj = append(obj, member{key.(string), value})
+		}
+
+		// closing brace
+		_, e = dec.Token()
+
+		return obj, e
+	case json.Delim('['):
+		list := []interface{}{}
+
+		for dec.More() {
+			value, e := decode(dec)
+
+			if e != nil {
+				return nil, e
+			}
+
+			list = append(list, value)
+		}
+
+		// closing bracket
+		_, e = dec.Token()
+
+		return list, e
+	default:
+		return token, nil
+	}
+}
+
+// check if a value is a non-empty object or array, written over several lines
+func isCollection(value interface{}) bool {
+	switch v := value.(type) {
+	case object:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+// write a non-empty object or array as block YAML at an indent
+func writeYAML(b *strings.Builder, value interface{}, indent string) {
+	switch v := value.(type) {
+	case object:
+		for _, m := range v {
+			b.WriteString(indent + scalar(m.key) + ":")
+			writeChild(b, m.value, indent)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if !isCollection(item) {
+				b.WriteString(indent + "- " + scalar(item) + "\n")
+				continue
+			}
+
+			// write the item one level deeper, then put the dash in
+			// place of the first line's indent
+			child := &strings.Builder{}
+			writeYAML(child, item, indent+YAML_INDENT)
+			b.WriteString(indent + "- " + strings.TrimPrefix(child.String(), indent+YAML_INDENT))
+		}
+	}
+}
+
+// write the value of an object member after its key
+func writeChild(b *strings.Builder, value interface{}, indent string) {
+	if isCollection(value) {
+		b.WriteString("\n")
+		writeYAML(b, value, indent+YAML_INDENT)
+	} else {
+		b.WriteString(" " + scalar(value) + "\n")
+	}
+}
+
+// format a scalar (or empty collection) as YAML
+func scalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+
+		return "false"
+	case json.Number:
+		return v.String()
+	case string:
+		if plainRe.MatchString(v) && !keywords[strings.ToLower(v)] {
+			return v
+		}
+
+		// JSON strings are valid double quoted YAML strings
+		quoted, _ := json.Marshal(v)
+
+		return string(quoted)
+	case object:
+		return "{}"
+	default:
+		return "[]"
+	}
+}
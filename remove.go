@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	R_FLAG_ORPHANS = "with-orphans"
+)
+
+// remove mods: delete their archives and drop them from the mod list
+func remove(flags *ModtorioFlags, options []string) error {
+	var withOrphans, dryRun bool
+
+	removeFlags := flag.NewFlagSet("Remove flags", flag.ContinueOnError)
+
+	removeFlags.BoolVar(&withOrphans, R_FLAG_ORPHANS, false, "Also remove dependencies that nothing else requires")
+	removeFlags.BoolVar(&dryRun, E_FLAG_DRY_RUN, false, "Show the mods that would be removed without removing them")
+	e := removeFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	if removeFlags.NArg() == 0 {
+		return fmt.Errorf("No mods specified")
+	}
+
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	mods, unmatched, e := list.Match(modlist.MATCH_EXACT, removeFlags.Args())
+
+	if e != nil {
+		return e
+	}
+
+	for _, mod := range mods {
+		if modlist.IsDLC(mod.Name) {
+			// DLC is part of the game and has no archive to remove
+			unmatched = append(unmatched, mod.Name)
+		}
+	}
+
+	if len(unmatched) > 0 {
+		return fmt.Errorf("Not removable or not found in the mod list: %s", strings.Join(unmatched, " "))
+	}
+
+	deps, e := readDependencies(flags.dir, list)
+
+	if e != nil {
+		return e
+	}
+
+	if withOrphans {
+		mods = append(mods, deps.orphans(list, mods)...)
+	}
+
+	// warn about the mods that will no longer load
+	for _, mod := range deps.dependents(list, mods) {
+		fmt.Printf("Warning: %s requires a removed mod and will fail to load\n", mod.Name)
+	}
+
+	// every version of a removed mod is deleted, not only the newest,
+	// so that older archives are not adopted again
+	all, _, e := modlist.ScanArchives(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	var names, archives []string
+	removed := map[string]bool{}
+
+	for _, mod := range mods {
+		names = append(names, mod.Name)
+		removed[mod.Name] = true
+	}
+
+	for _, archive := range all {
+		if removed[archive.Mod] {
+			archives = append(archives, archive.File)
+		}
+	}
+
+	printNames("Removing", names)
+	printNames("Deleting archives", archives)
+
+	if dryRun {
+		fmt.Println("Dry run: no changes made")
+
+		return nil
+	}
+
+	fmt.Println()
+	ok, e := confirm()
+
+	if e != nil {
+		return e
+	}
+
+	if !ok {
+		return fmt.Errorf("Remove cancelled")
+	}
+
+	// drop the entries first, so an interrupted removal does not
+	// leave mods in the list without archives
+	list.Remove(names...)
+	e = list.Write(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	for _, archive := range archives {
+		e = os.Remove(filepath.Join(flags.dir, archive))
+
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+// re-download the installed version of every mod in the list whose archive is
+// missing, broken or does not match the mod portal. mods without an archive
+// get the newest release for the factorio version. enabled states are kept
+func repair(flags *ModtorioFlags, options []string) error {
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	results, e := verifyArchives(flags.dir, list, true)
+
+	if e != nil {
+		return e
+	}
+
+	// broken mods in the list, by name
+	broken := map[string]*verifyResult{}
+	var names []string
+
+	for _, result := range results {
+		mod := list.Get(result.mod)
+
+		if len(result.issues) == 0 || mod == nil || modlist.IsDLC(mod.Name) {
+			continue
+		}
+
+		if result.file != "" && (mod.Archive == nil || mod.Archive.File != result.file) {
+			// an old version of the mod. clean removes these
+			continue
+		}
+
+		broken[mod.Name] = result
+		names = append(names, mod.Name)
+	}
+
+	if len(names) == 0 {
+		fmt.Println("Nothing to repair")
+
+		return nil
+	}
+
+	portal, e := api.GetAll(names...)
+
+	if e != nil {
+		return e
+	}
+
+	var downloads []*api.Release
+	var replaced, unavailable []string
+
+	for _, name := range names {
+		result := broken[name]
+		var release *api.Release
+
+		if result.file != "" {
+			release = findRelease(portal, name, result.version)
+		} else if pinned := list.Get(name).PinnedVersion(); pinned != "" {
+			release = findRelease(portal, name, pinned)
+		} else {
+			release = newestRelease(portal, name, flags.factorio)
+
+			if release != nil {
+				fmt.Printf("%s has no archive or pinned version, downloading the newest release %s\n", name, release.Version)
+			}
+		}
+
+		if release == nil {
+			unavailable = append(unavailable, name)
+			continue
+		}
+
+		fmt.Printf("Repairing %s (%s)\n", release.File_name, strings.Join(result.issues, ", "))
+		downloads = append(downloads, release)
+
+		if result.file != "" {
+			replaced = append(replaced, result.file)
+		}
+	}
+
+	if len(unavailable) > 0 {
+		fmt.Printf("Not available on the mod portal: %s\n", strings.Join(unavailable, " "))
+	}
+
+	e = downloadReleases(flags.dir, downloads, replaced, nil, nil)
+
+	if e != nil {
+		return e
+	}
+
+	fmt.Printf("Repaired %d mods\n", len(downloads))
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"time"
+
+	"github.com/blacksfk/modtorio/journal"
+	"github.com/blacksfk/modtorio/modlist"
+	"github.com/blacksfk/modtorio/snapshot"
+)
+
+const (
+	OUTPUT_VERSION = 1 // incremented when the structured output changes incompatibly
+)
+
+// the structured result of the command being run. see emit
+var result interface{}
+
+// set the structured result of the command, written with --output json|yaml
+func emit(v interface{}) {
+	result = v
+}
+
+// the document written with --output json|yaml
+type envelope struct {
+	Version int         `json:"version"`
+	Command string      `json:"command"`
+	Ok      bool        `json:"ok"`
+	Error   string      `json:"error,omitempty"`
+	Result  interface{} `json:"result"`
+}
+
+func newEnvelope(cmd string, e error) *envelope {
+	env := &envelope{Version: OUTPUT_VERSION, Command: cmd, Ok: e == nil, Result: result}
+
+	if e != nil {
+		env.Error = e.Error()
+	}
+
+	return env
+}
+
+// a journal entry: the changes made by a command that modifies the mods directory
+type journalEntry struct {
+	Id              int       `json:"id"`
+	Time            time.Time `json:"time"`
+	User            string    `json:"user"`
+	Args            []string  `json:"args"`
+	Error           string    `json:"error,omitempty"`
+	Undone          bool      `json:"undone"`
+	ArchivesAdded   []string  `json:"archives_added"`
+	ArchivesRemoved []string  `json:"archives_removed"`
+	ModsAdded       []string  `json:"mods_added"`
+	ModsRemoved     []string  `json:"mods_removed"`
+	Enabled         []string  `json:"enabled"`
+	Disabled        []string  `json:"disabled"`
+}
+
+// convert a journal entry for output. returns nil if entry is nil
+func newJournalEntry(entry *journal.Entry) *journalEntry {
+	if entry == nil {
+		return nil
+	}
+
+	return &journalEntry{
+		Id:              entry.Id,
+		Time:            entry.Time,
+		User:            entry.User,
+		Args:            nonNil(entry.Args),
+		Error:           entry.Error,
+		Undone:          entry.Undone,
+		ArchivesAdded:   nonNil(entry.Added),
+		ArchivesRemoved: nonNil(entry.Removed),
+		ModsAdded:       nonNil(entry.ModsAdded),
+		ModsRemoved:     nonNil(entry.ModsRemoved),
+		Enabled:         nonNil(entry.Enabled),
+		Disabled:        nonNil(entry.Disabled),
+	}
+}
+
+func newJournalEntries(entries []*journal.Entry) []*journalEntry {
+	converted := []*journalEntry{}
+
+	for _, entry := range entries {
+		converted = append(converted, newJournalEntry(entry))
+	}
+
+	return converted
+}
+
+// lists are always written as arrays, never null
+func nonNil(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+
+	return names
+}
+
+// a mod in the mod list
+type modEntry struct {
+	Name    string  `json:"name"`
+	Enabled bool    `json:"enabled"`
+	Version *string `json:"version"` // version of the installed archive, null if there is none
+	Archive *string `json:"archive"`
+}
+
+func newModEntry(mod *modlist.Mod) *modEntry {
+	entry := &modEntry{Name: mod.Name, Enabled: mod.Enabled}
+
+	if mod.Archive != nil {
+		entry.Version = &mod.Archive.Version
+		entry.Archive = &mod.Archive.File
+	}
+
+	return entry
+}
+
+// a snapshot of the mods directory
+type snapshotEntry struct {
+	Name     string    `json:"name"`
+	Created  time.Time `json:"created"`
+	Archives []string  `json:"archives"`
+	Size     int64     `json:"size"`
+}
+
+func newSnapshotEntry(s *snapshot.Snapshot) *snapshotEntry {
+	return &snapshotEntry{s.Name, s.Created, nonNil(s.Archives), s.Size()}
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"regexp"
+	"text/template"
 
 	"github.com/blacksfk/modtorio/api"
 )
@@ -43,14 +44,29 @@ func (sre *SearchRE) match(result *api.Result) bool {
 func search(flags *ModtorioFlags, options []string) error {
 	var nameOnly bool
 	var sres []*SearchRE
-	var strOwner, strTag string
+	var strOwner, strTag, format string
 
 	searchFlags := flag.NewFlagSet("Search flags", flag.ContinueOnError)
 
 	searchFlags.BoolVar(&nameOnly, S_FLAG_NAME, false, "Print a space-delimited list of mod names")
 	searchFlags.StringVar(&strOwner, S_FLAG_OWNER, "", "Match a mod by owner")
 	searchFlags.StringVar(&strTag, S_FLAG_TAG, "", "Match a mod by tag")
-	searchFlags.Parse(options)
+	searchFlags.StringVar(&format, F_FLAG_FORMAT, "", "Print each mod with a Go text/template")
+	e := searchFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	var t *template.Template
+
+	if format != "" {
+		t, e = parseFormat(format)
+
+		if e != nil {
+			return e
+		}
+	}
 
 	sres = compileAndAppend(strOwner, matchOwner, sres)
 	sres = compileAndAppend(strTag, matchTag, sres)
@@ -67,7 +83,7 @@ func search(flags *ModtorioFlags, options []string) error {
 		return e
 	}
 
-	var matches []*api.Result
+	matches := []*api.Result{}
 	matchCount := 0
 
 	// for each mod result:
@@ -91,7 +107,17 @@ func search(flags *ModtorioFlags, options []string) error {
 		}
 	}
 
-	if nameOnly {
+	emit(matches)
+
+	if t != nil {
+		for _, match := range matches {
+			e = printFormatted(t, match)
+
+			if e != nil {
+				return e
+			}
+		}
+	} else if nameOnly {
 		// print all matched mods' names on a single line
 		for i := 0; i < matchCount; i++ {
 			fmt.Print(matches[i].Name)
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/lock"
+	"github.com/blacksfk/modtorio/snapshot"
+)
+
+const (
+	SNAPSHOT_TIME_FORMAT = "2006-01-02 15:04:05"
+)
+
+// take a snapshot of the mods directory
+func takeSnapshot(flags *ModtorioFlags, options []string) error {
+	// snapshots do not modify the directory, but must not be
+	// taken while another run is modifying it
+	l, e := lock.Acquire(flags.dir, flags.wait)
+
+	if e != nil {
+		return e
+	}
+
+	defer l.Release()
+
+	var name string
+
+	if len(options) > 0 {
+		name = options[0]
+	}
+
+	s, e := snapshot.Take(flags.dir, name)
+
+	if e != nil {
+		return e
+	}
+
+	emit(newSnapshotEntry(s))
+	fmt.Printf("Snapshot %s taken (%d archives)\n", s.Name, len(s.Archives))
+
+	return nil
+}
+
+// list all snapshots of the mods directory
+func listSnapshots(flags *ModtorioFlags, options []string) error {
+	snapshots, e := snapshot.List(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	entries := []*snapshotEntry{}
+
+	for _, s := range snapshots {
+		entries = append(entries, newSnapshotEntry(s))
+	}
+
+	emit(entries)
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots")
+
+		return nil
+	}
+
+	// default to 4 for "Name" header
+	longest := 4
+
+	for _, s := range snapshots {
+		if l := len(s.Name); l > longest {
+			longest = l
+		}
+	}
+
+	fmt.Printf("%-*s  %-19s  %8s  %s\n", longest, "Name", "Created", "Archives", "Size")
+
+	for _, s := range snapshots {
+		fmt.Printf("%-*s  %-19s  %8d  %s\n", longest, s.Name, s.Created.Format(SNAPSHOT_TIME_FORMAT), len(s.Archives), common.FormatSize(s.Size()))
+	}
+
+	return nil
+}
+
+// restore the mods directory to a snapshot
+func restore(flags *ModtorioFlags, options []string) error {
+	s, e := snapshot.Get(flags.dir, options[0])
+
+	if e != nil {
+		return e
+	}
+
+	current, e := snapshot.ListArchives(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	// print a summary of what will change
+	var added, removed []string
+
+	for _, archive := range s.Archives {
+		if !contains(current, archive) {
+			added = append(added, archive)
+		}
+	}
+
+	for _, archive := range current {
+		if !contains(s.Archives, archive) {
+			removed = append(removed, archive)
+		}
+	}
+
+	fmt.Printf("Restoring snapshot %s taken %s\n", s.Name, s.Created.Format(time.RFC1123))
+	printNames("Restore", added)
+	printNames("Remove", removed)
+	fmt.Printf("mod-list.json and mod-settings.dat will be replaced\n\n")
+
+	ok, e := confirm()
+
+	if e != nil {
+		return e
+	}
+
+	if !ok {
+		return fmt.Errorf("Restore cancelled")
+	}
+
+	return s.Restore(flags.dir)
+}
+
+// take a snapshot before an update, removing the oldest automatic
+// snapshots so that only keep remain
+func autoSnapshot(dir string, keep int) error {
+	s, e := snapshot.Take(dir, snapshot.AUTO_PREFIX+time.Now().Format(snapshot.TIME_FORMAT))
+
+	if e != nil {
+		return e
+	}
+
+	fmt.Printf("Snapshot %s taken\n", s.Name)
+	_, e = snapshot.Prune(dir, snapshot.AUTO_PREFIX, keep)
+
+	return e
+}
+
+// get a function taking an automatic snapshot of dir, to be called once an
+// update is confirmed. returns nil if snapshots are disabled
+func snapshotBefore(dir string, noSnapshot bool, keep int) func() error {
+	if noSnapshot {
+		return nil
+	}
+
+	return func() error {
+		return autoSnapshot(dir, keep)
+	}
+}
+
+// print a labelled, space-delimited list of names (if there are any)
+func printNames(label string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Printf("%s (%d):", label, len(names))
+
+	for _, name := range names {
+		fmt.Printf(" %s", name)
+	}
+
+	fmt.Println()
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
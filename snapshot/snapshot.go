@@ -0,0 +1,318 @@
+/*
+Package to snapshot and restore the state of a mods directory:
+mod-list.json, mod-settings.dat and the set of mod archives.
+*/
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	DIR         = "snapshots"
+	MANIFEST    = "snapshot.json"
+	SETTINGS    = "mod-settings.dat"
+	AUTO_PREFIX = "auto-" // prefix of snapshots taken automatically
+	TIME_FORMAT = "2006-01-02T15-04-05"
+	MODE        = 0644
+)
+
+// files other than archives recorded in a snapshot
+var files = []string{modlist.FILE_NAME, SETTINGS}
+
+type Snapshot struct {
+	Name     string
+	Created  time.Time
+	Files    []string // files (of the above) present when the snapshot was taken
+	Archives []string // archive file names
+
+	path string // directory holding the snapshot
+}
+
+// Take a snapshot of a mods directory. If name is empty the current time is used.
+// Archives are hard-linked into the snapshot where possible (they are never
+// modified in place) and copied otherwise.
+func Take(dir, name string) (*Snapshot, error) {
+	if name == "" {
+		name = time.Now().Format(TIME_FORMAT)
+	}
+
+	e := validateName(name)
+
+	if e != nil {
+		return nil, e
+	}
+
+	root, e := common.MkStateDir(dir, DIR)
+
+	if e != nil {
+		return nil, e
+	}
+
+	path := filepath.Join(root, name)
+
+	if _, e := os.Stat(path); e == nil {
+		return nil, fmt.Errorf("Snapshot %s already exists", name)
+	}
+
+	// build the snapshot under a temporary name so that a failure
+	// part way through does not leave a broken snapshot behind
+	tmp, e := os.MkdirTemp(root, common.TEMP_PREFIX+"*")
+
+	if e != nil {
+		return nil, e
+	}
+
+	defer os.RemoveAll(tmp)
+
+	s := &Snapshot{Name: name, Created: time.Now(), path: path}
+	s.Archives, e = ListArchives(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	for _, archive := range s.Archives {
+		e = common.LinkOrCopy(filepath.Join(dir, archive), filepath.Join(tmp, archive))
+
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	for _, file := range files {
+		e = common.CopyFile(filepath.Join(dir, file), filepath.Join(tmp, file), MODE)
+
+		if os.IsNotExist(e) {
+			// not every directory has mod settings (or a mod list)
+			continue
+		} else if e != nil {
+			return nil, e
+		}
+
+		s.Files = append(s.Files, file)
+	}
+
+	bytes, e := json.MarshalIndent(s, "", "\t")
+
+	if e != nil {
+		return nil, e
+	}
+
+	e = os.WriteFile(filepath.Join(tmp, MANIFEST), bytes, MODE)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return s, os.Rename(tmp, path)
+}
+
+// Get a snapshot by name.
+func Get(dir, name string) (*Snapshot, error) {
+	e := validateName(name)
+
+	if e != nil {
+		return nil, e
+	}
+
+	path := common.StatePath(dir, DIR, name)
+	bytes, e := os.ReadFile(filepath.Join(path, MANIFEST))
+
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, fmt.Errorf("Snapshot %s not found", name)
+		}
+
+		return nil, e
+	}
+
+	s := &Snapshot{path: path}
+
+	return s, json.Unmarshal(bytes, s)
+}
+
+// List all snapshots of a mods directory, oldest first.
+func List(dir string) ([]*Snapshot, error) {
+	entries, e := os.ReadDir(common.StatePath(dir, DIR))
+
+	if e != nil {
+		if os.IsNotExist(e) {
+			// no snapshots taken yet
+			return nil, nil
+		}
+
+		return nil, e
+	}
+
+	var snapshots []*Snapshot
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), common.TEMP_PREFIX) {
+			continue
+		}
+
+		s, e := Get(dir, entry.Name())
+
+		if e != nil {
+			// not a snapshot or a broken one, skip it
+			continue
+		}
+
+		snapshots = append(snapshots, s)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+
+	return snapshots, nil
+}
+
+// Remove the oldest snapshots whose names start with prefix so that only
+// keep remain (none if keep is 0). Returns the names of the removed snapshots.
+func Prune(dir, prefix string, keep int) ([]string, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("Cannot keep %d snapshots", keep)
+	}
+
+	snapshots, e := List(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	var matching []*Snapshot
+
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Name, prefix) {
+			matching = append(matching, s)
+		}
+	}
+
+	var removed []string
+
+	for i := 0; i < len(matching)-keep; i++ {
+		e = os.RemoveAll(matching[i].path)
+
+		if e != nil {
+			return removed, e
+		}
+
+		removed = append(removed, matching[i].Name)
+	}
+
+	return removed, nil
+}
+
+// Restore a mods directory to the state recorded in the snapshot. Archives not
+// in the snapshot are removed, missing archives are restored, and
+// mod-list.json and mod-settings.dat are replaced (or removed if they did not
+// exist when the snapshot was taken).
+func (s *Snapshot) Restore(dir string) error {
+	current, e := ListArchives(dir)
+
+	if e != nil {
+		return e
+	}
+
+	// put the snapshot's archives back first, so a failure does not leave
+	// the directory with fewer archives than before
+	for _, archive := range s.Archives {
+		e = common.Place(filepath.Join(s.path, archive), filepath.Join(dir, archive), true)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	for _, archive := range current {
+		if !contains(s.Archives, archive) {
+			e = os.Remove(filepath.Join(dir, archive))
+
+			if e != nil {
+				return e
+			}
+		}
+	}
+
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+
+		if contains(s.Files, file) {
+			e = common.Place(filepath.Join(s.path, file), path, false)
+		} else {
+			e = os.Remove(path)
+
+			if os.IsNotExist(e) {
+				e = nil
+			}
+		}
+
+		if e != nil {
+			return e
+		}
+	}
+
+	return common.SyncDir(dir)
+}
+
+// Get the names of all archives in a mods directory.
+func ListArchives(dir string) ([]string, error) {
+	entries, e := os.ReadDir(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	var archives []string
+
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), modlist.ARCHIVE_EXT) {
+			archives = append(archives, entry.Name())
+		}
+	}
+
+	return archives, nil
+}
+
+// get the size of all of the archives in the snapshot
+func (s *Snapshot) Size() int64 {
+	var size int64
+
+	for _, archive := range s.Archives {
+		if info, e := os.Stat(filepath.Join(s.path, archive)); e == nil {
+			size += info.Size()
+		}
+	}
+
+	return size
+}
+
+// snapshot names are used as directory names
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, common.TEMP_PREFIX) {
+		return fmt.Errorf("Invalid snapshot name: %s", name)
+	}
+
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTakeRestore(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name, data string) {
+		e := os.WriteFile(filepath.Join(dir, name), []byte(data), MODE)
+
+		if e != nil {
+			t.Fatal("TestTakeRestore:", e)
+		}
+	}
+
+	write("mod-list.json", "before")
+	write("a_1.0.0.zip", "a")
+	write("b_1.0.0.zip", "b")
+
+	s, e := Take(dir, "test")
+
+	if e != nil {
+		t.Fatal("TestTakeRestore:", e)
+	}
+
+	if _, e = Take(dir, "test"); e == nil {
+		t.Error("Take() with an existing name should fail")
+	}
+
+	// change the directory: update a, remove b, add settings
+	os.Remove(filepath.Join(dir, "a_1.0.0.zip"))
+	os.Remove(filepath.Join(dir, "b_1.0.0.zip"))
+	write("a_1.1.0.zip", "a2")
+	write("mod-list.json", "after")
+	write(SETTINGS, "settings")
+
+	e = s.Restore(dir)
+
+	if e != nil {
+		t.Fatal("TestTakeRestore:", e)
+	}
+
+	archives, e := ListArchives(dir)
+
+	if e != nil {
+		t.Fatal("TestTakeRestore:", e)
+	}
+
+	if len(archives) != 2 || archives[0] != "a_1.0.0.zip" || archives[1] != "b_1.0.0.zip" {
+		t.Errorf("Restore() left archives: %v", archives)
+	}
+
+	if bytes, _ := os.ReadFile(filepath.Join(dir, "mod-list.json")); string(bytes) != "before" {
+		t.Errorf("Restore() mod-list.json = %s, expected: before", bytes)
+	}
+
+	if _, e = os.Stat(filepath.Join(dir, SETTINGS)); !os.IsNotExist(e) {
+		t.Errorf("Restore() did not remove %s", SETTINGS)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"auto-1", "manual", "auto-2", "auto-3"} {
+		if _, e := Take(dir, name); e != nil {
+			t.Fatal("TestPrune:", e)
+		}
+	}
+
+	removed, e := Prune(dir, AUTO_PREFIX, 2)
+
+	if e != nil {
+		t.Fatal("TestPrune:", e)
+	}
+
+	if len(removed) != 1 || removed[0] != "auto-1" {
+		t.Errorf("Prune() removed %v, expected: [auto-1]", removed)
+	}
+
+	snapshots, e := List(dir)
+
+	if e != nil {
+		t.Fatal("TestPrune:", e)
+	}
+
+	if len(snapshots) != 3 {
+		t.Errorf("List() after Prune() = %d snapshots, expected: 3", len(snapshots))
+	}
+}
+
+func TestPruneKeep(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"auto-1", "auto-2", "manual"} {
+		if _, e := Take(dir, name); e != nil {
+			t.Fatal("TestPruneKeep:", e)
+		}
+	}
+
+	if _, e := Prune(dir, AUTO_PREFIX, -1); e == nil {
+		t.Error("Prune() keeping -1 snapshots should fail")
+	}
+
+	removed, e := Prune(dir, AUTO_PREFIX, 0)
+
+	if e != nil {
+		t.Fatal("TestPruneKeep:", e)
+	}
+
+	if len(removed) != 2 {
+		t.Errorf("Prune() keeping 0 removed %v, expected: [auto-1 auto-2]", removed)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Package to stage archives before moving them into a mods directory, so that
+a set of changes is applied together or not at all.
+*/
+package stage
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	DIR     = "staging"
+	OLD_DIR = "old" // archives replaced during a commit
+)
+
+// a staging area for archives. archives are written to Path, and moved into
+// the mods directory by Commit
+type Stage struct {
+	Path string
+	dir  string
+}
+
+// a file moved during a commit, so that it can be moved back
+type move struct {
+	from, to string
+}
+
+// Create a staging area for a mods directory. The staging area is within
+// the mods directory so that archives can be renamed into place.
+func New(dir string) (*Stage, error) {
+	root, e := common.MkStateDir(dir, DIR)
+
+	if e != nil {
+		return nil, e
+	}
+
+	path, e := os.MkdirTemp(root, common.TEMP_PREFIX+"*"+common.TEMP_SUFFIX)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return &Stage{path, dir}, nil
+}
+
+// Get the path of the directory holding stale staging areas (see
+// common.RemoveStaleTemp).
+func Root(dir string) string {
+	return common.StatePath(dir, DIR)
+}
+
+// Move every staged archive into the mods directory, remove the replaced
+// archives (file names within the mods directory), and write the mod list (if
+// not nil). If any step fails every step before it is undone, leaving the mods
+// directory as it was.
+func (s *Stage) Commit(replaced []string, list *modlist.ModList) error {
+	entries, e := os.ReadDir(s.Path)
+
+	if e != nil {
+		return e
+	}
+
+	old := filepath.Join(s.Path, OLD_DIR)
+	e = os.Mkdir(old, common.STATE_DIR_MODE)
+
+	if e != nil {
+		return e
+	}
+
+	var moves []move
+
+	// move a file, recording it so that it can be undone
+	mv := func(from, to string) error {
+		e := os.Rename(from, to)
+
+		if e == nil {
+			moves = append(moves, move{from, to})
+		}
+
+		return e
+	}
+
+	// move the archives being replaced (or overwritten) out of the way.
+	// they are only deleted once everything else has succeeded
+	for _, entry := range entries {
+		replaced = append(replaced, entry.Name())
+	}
+
+	for _, name := range replaced {
+		e = mv(filepath.Join(s.dir, name), filepath.Join(old, name))
+
+		if os.IsNotExist(e) {
+			// nothing to replace (or replaced twice)
+			continue
+		} else if e != nil {
+			return s.rollback(moves, e)
+		}
+	}
+
+	// move the new archives into place
+	for _, entry := range entries {
+		e = mv(filepath.Join(s.Path, entry.Name()), filepath.Join(s.dir, entry.Name()))
+
+		if e != nil {
+			return s.rollback(moves, e)
+		}
+	}
+
+	if list != nil {
+		e = list.Write(s.dir)
+
+		if e != nil {
+			return s.rollback(moves, e)
+		}
+	}
+
+	e = common.SyncDir(s.dir)
+
+	if e != nil {
+		return s.rollback(moves, e)
+	}
+
+	// committed, the replaced archives are no longer needed
+	return s.Abort()
+}
+
+// Remove the staging area and anything left in it.
+func (s *Stage) Abort() error {
+	return os.RemoveAll(s.Path)
+}
+
+// undo moves in reverse order and return the error that caused the rollback
+func (s *Stage) rollback(moves []move, cause error) error {
+	for i := len(moves) - 1; i >= 0; i-- {
+		os.Rename(moves[i].to, moves[i].from)
+	}
+
+	return cause
+}
@@ -0,0 +1,90 @@
+package stage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+// write a file into a directory
+func write(t *testing.T, dir, name string) {
+	e := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+}
+
+func exists(dir, name string) bool {
+	_, e := os.Stat(filepath.Join(dir, name))
+
+	return e == nil
+}
+
+func TestCommit(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "a_1.0.0.zip")
+
+	s, e := New(dir)
+
+	if e != nil {
+		t.Fatal("TestCommit:", e)
+	}
+
+	write(t, s.Path, "a_1.1.0.zip")
+	write(t, s.Path, "b_1.0.0.zip")
+
+	list := &modlist.ModList{}
+	list.Add("a", "b")
+	e = s.Commit([]string{"a_1.0.0.zip"}, list)
+
+	if e != nil {
+		t.Fatal("TestCommit:", e)
+	}
+
+	for name, expected := range map[string]bool{
+		"a_1.0.0.zip":     false,
+		"a_1.1.0.zip":     true,
+		"b_1.0.0.zip":     true,
+		modlist.FILE_NAME: true,
+	} {
+		if exists(dir, name) != expected {
+			t.Errorf("Commit(): %s exists = %t, expected: %t", name, !expected, expected)
+		}
+	}
+
+	if _, e = os.Stat(s.Path); !os.IsNotExist(e) {
+		t.Errorf("Commit() did not remove the staging area")
+	}
+}
+
+func TestCommitRollback(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "a_1.0.0.zip")
+
+	// make writing the mod list fail
+	e := os.MkdirAll(filepath.Join(dir, modlist.FILE_NAME, "blocker"), 0755)
+
+	if e != nil {
+		t.Fatal("TestCommitRollback:", e)
+	}
+
+	s, e := New(dir)
+
+	if e != nil {
+		t.Fatal("TestCommitRollback:", e)
+	}
+
+	write(t, s.Path, "a_1.1.0.zip")
+	e = s.Commit([]string{"a_1.0.0.zip"}, &modlist.ModList{})
+
+	if e == nil {
+		t.Fatal("Commit() succeeded, expected an error")
+	}
+
+	if !exists(dir, "a_1.0.0.zip") || exists(dir, "a_1.1.0.zip") {
+		t.Errorf("Commit() did not roll back the archives")
+	}
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+
 	"github.com/blacksfk/modtorio/api"
 	"github.com/blacksfk/modtorio/common"
 	"github.com/blacksfk/modtorio/modlist"
 )
 
+const (
+	U_FLAG_NO_SNAPSHOT = "no-snapshot"
+	U_FLAG_KEEP        = "keep-snapshots"
+	KEEP_SNAPSHOTS     = 5
+)
+
 type ModResult struct {
 	*modlist.Mod
 	*api.Result
@@ -26,9 +35,84 @@ func (mr *ModResult) FindRelease(factorio *common.Semver) *api.Release {
 	return nil
 }
 
+// find the api result of a mod. returns nil if not found
+func findResult(results []*api.Result, name string) *api.Result {
+	for _, result := range results {
+		if result.Name == name {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// find a specific release of a mod in the api results. returns nil if not found
+func findRelease(results []*api.Result, name, version string) *api.Release {
+	for _, result := range results {
+		if result.Name != name {
+			continue
+		}
+
+		for _, release := range result.Releases {
+			if release.Version == version {
+				return release
+			}
+		}
+	}
+
+	return nil
+}
+
+// find the newest release of a mod for the factorio version in the api
+// results. returns nil if not found
+func newestRelease(results []*api.Result, name string, factorio *common.Semver) *api.Release {
+	for _, result := range results {
+		if result.Name != name {
+			continue
+		}
+
+		for i := len(result.Releases) - 1; i >= 0; i-- {
+			if result.Releases[i].CmpFactorioVersion(factorio) == 0 {
+				return result.Releases[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// check the number of automatic snapshots to keep. the snapshot taken
+// before an update must be kept so that it can be restored
+func checkKeep(keep int) error {
+	if keep < 1 {
+		return fmt.Errorf("--%s must be at least 1, found: %d", U_FLAG_KEEP, keep)
+	}
+
+	return nil
+}
+
 func update(flags *ModtorioFlags, options []string) error {
+	var noSnapshot bool
+	var keep int
+
+	updateFlags := flag.NewFlagSet("Update flags", flag.ContinueOnError)
+
+	updateFlags.BoolVar(&noSnapshot, U_FLAG_NO_SNAPSHOT, false, "Do not take a snapshot before updating")
+	updateFlags.IntVar(&keep, U_FLAG_KEEP, KEEP_SNAPSHOTS, "Number of automatic snapshots to keep")
+	e := updateFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	e = checkKeep(keep)
+
+	if e != nil {
+		return e
+	}
+
 	// first, get a list of mods
-	list, e := modlist.Read(flags.dir)
+	list, e := modlist.Read(flags.dir, flags.factorio)
 
 	if e != nil {
 		return e
@@ -93,15 +177,22 @@ func update(flags *ModtorioFlags, options []string) error {
 	// second, loop through all of the mod results and generate an array of
 	// releases to download
 	var downloads []*api.Release
+	var replaced []string
 
 	for _, mr := range modResults {
 		release := mr.FindRelease(flags.factorio)
 
 		if release != nil {
 			downloads = append(downloads, release)
+
+			if mr.Archive != nil {
+				// the old archive is removed once the new one is in place
+				replaced = append(replaced, mr.Archive.File)
+			}
 		}
 	}
 
-	// last, attempt to login and download the releases
-	return downloadReleases(flags.dir, downloads)
+	// last, attempt to login and download the releases, taking a snapshot
+	// before they are installed so the update can be rolled back with restore
+	return downloadReleases(flags.dir, downloads, replaced, nil, snapshotBefore(flags.dir, noSnapshot, keep))
 }
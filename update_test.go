@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeepSnapshots(t *testing.T) {
+	for _, keep := range []int{0, -1} {
+		options := []string{"--" + U_FLAG_KEEP, strconv.Itoa(keep)}
+
+		// rejected before anything else is read
+		if e := update(&ModtorioFlags{}, options); e == nil || !strings.Contains(e.Error(), U_FLAG_KEEP) {
+			t.Errorf("update() with %v = %v, expected an error about --%s", options, e, U_FLAG_KEEP)
+		}
+
+		options = append(options, "--"+P_FLAG_TO, "2.0")
+
+		if e := upgradePlan(&ModtorioFlags{}, options); e == nil || !strings.Contains(e.Error(), U_FLAG_KEEP) {
+			t.Errorf("upgradePlan() with %v = %v, expected an error about --%s", options, e, U_FLAG_KEEP)
+		}
+	}
+
+	if e := checkKeep(1); e != nil {
+		t.Error("checkKeep(1):", e)
+	}
+}
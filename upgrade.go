@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	P_FLAG_TO    = "to"
+	P_FLAG_APPLY = "apply"
+
+	// upgrade plan statuses
+	PLAN_READY   = "ready"   // has a release for the target version
+	PLAN_BLOCKED = "blocked" // on the portal, but no release for the target version
+	PLAN_MISSING = "missing" // not on the portal
+)
+
+// the outcome of upgrading a mod to a game version
+type planItem struct {
+	mod     *modlist.Mod
+	status  string
+	release *api.Release // the release for the target version (if ready)
+	detail  string
+}
+
+// a plan item for output
+type planEntry struct {
+	Name    string  `json:"name"`
+	Enabled bool    `json:"enabled"`
+	Status  string  `json:"status"`
+	From    *string `json:"from"` // installed version, null if there is no archive
+	To      *string `json:"to"`   // version for the target game version, null unless ready
+	Detail  string  `json:"detail"`
+}
+
+// check every mod in the list has a release for another game version, and
+// optionally download those releases once they all do
+func upgradePlan(flags *ModtorioFlags, options []string) error {
+	var to string
+	var apply, noSnapshot bool
+	var keep int
+
+	planFlags := flag.NewFlagSet("Upgrade plan flags", flag.ContinueOnError)
+
+	planFlags.StringVar(&to, P_FLAG_TO, "", "Game version to upgrade to, eg. 2.0 or stable")
+	planFlags.BoolVar(&apply, P_FLAG_APPLY, false, "Download the releases for the target version if every mod is ready")
+	planFlags.BoolVar(&noSnapshot, U_FLAG_NO_SNAPSHOT, false, "Do not take a snapshot before applying the upgrade")
+	planFlags.IntVar(&keep, U_FLAG_KEEP, KEEP_SNAPSHOTS, "Number of automatic snapshots to keep")
+	e := planFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	e = checkKeep(keep)
+
+	if e != nil {
+		return e
+	}
+
+	if to == "" {
+		return fmt.Errorf("No target version specified, eg. --%s 2.0", P_FLAG_TO)
+	}
+
+	if api.IsChannel(to) {
+		to, e = resolveChannel(to)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	target, e := common.NewSemver(to)
+
+	if e != nil {
+		return e
+	}
+
+	// releases only list the major and minor versions they support
+	target.Patch = 0
+
+	if !apply {
+		return showUpgradePlan(flags, target, false, noSnapshot, keep)
+	}
+
+	// the upgrade modifies the mods directory, so run it as a mutating
+	// command. the plan is made while holding the lock so that it matches
+	// the archives it replaces
+	cmd := Command{CMD_UPGRADE_PLAN, 0, true, func(flags *ModtorioFlags, options []string) error {
+		return showUpgradePlan(flags, target, true, noSnapshot, keep)
+	}}
+
+	return run(cmd, flags, options)
+}
+
+// plan the upgrade of the mods directory to the target version and print
+// it, downloading the releases if apply is true and every mod is ready
+func showUpgradePlan(flags *ModtorioFlags, target *common.Semver, apply, noSnapshot bool, keep int) error {
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	e = list.FindArchives(flags.dir)
+
+	if e != nil {
+		return e
+	}
+
+	plan, e := planUpgrade(list, target)
+
+	if e != nil {
+		return e
+	}
+
+	if len(plan) == 0 {
+		fmt.Println("No mods to upgrade")
+
+		return nil
+	}
+
+	entries := []*planEntry{}
+
+	for _, item := range plan {
+		entry := &planEntry{Name: item.mod.Name, Enabled: item.mod.Enabled, Status: item.status, Detail: item.detail}
+
+		if item.mod.Archive != nil {
+			entry.From = &item.mod.Archive.Version
+		}
+
+		if item.release != nil {
+			entry.To = &item.release.Version
+		}
+
+		entries = append(entries, entry)
+	}
+
+	emit(entries)
+	counts := printPlan(plan, target)
+
+	if !apply {
+		return nil
+	}
+
+	if counts[PLAN_BLOCKED]+counts[PLAN_MISSING] > 0 {
+		return fmt.Errorf("Not upgrading: %d mods are blocked or missing", counts[PLAN_BLOCKED]+counts[PLAN_MISSING])
+	}
+
+	return applyUpgrade(flags.dir, plan, noSnapshot, keep)
+}
+
+// find the release of every mod in the list for the target version
+func planUpgrade(list *modlist.ModList, target *common.Semver) ([]*planItem, error) {
+	names := list.GetAllModNames()
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	results, e := api.GetAll(names...)
+
+	if e != nil {
+		return nil, e
+	}
+
+	var plan []*planItem
+
+	for _, mod := range list.Mods {
+		item := &planItem{mod: mod}
+		item.release = newestRelease(results, mod.Name, target)
+
+		if item.release != nil {
+			item.status = PLAN_READY
+			item.detail = upgradeDetail(mod, item.release)
+		} else if result := findResult(results, mod.Name); result != nil {
+			item.status = PLAN_BLOCKED
+			item.detail = "no release for this version"
+
+			if latest := result.Latest_release; latest != nil {
+				item.detail = fmt.Sprintf("newest release %s is for factorio %s (%s)", latest.Version, latest.Info_json.Factorio_version, latest.Released_at)
+			}
+		} else {
+			item.status = PLAN_MISSING
+			item.detail = "not on the mod portal"
+		}
+
+		plan = append(plan, item)
+	}
+
+	return plan, nil
+}
+
+// describe the version change of a mod
+func upgradeDetail(mod *modlist.Mod, release *api.Release) string {
+	if mod.Archive == nil {
+		return "install " + release.Version
+	}
+
+	if mod.Archive.Version == release.Version {
+		return release.Version + " (no change)"
+	}
+
+	return mod.Archive.Version + " -> " + release.Version
+}
+
+// print the plan and return the number of mods with each status
+func printPlan(plan []*planItem, target *common.Semver) map[string]int {
+	counts := map[string]int{}
+	longest := 0
+
+	for _, item := range plan {
+		if l := len(item.mod.Name); l > longest {
+			longest = l
+		}
+	}
+
+	fmt.Printf("Upgrade plan for factorio %d.%d:\n\n", target.Major, target.Minor)
+
+	for _, item := range plan {
+		counts[item.status]++
+		enabled := ""
+
+		if !item.mod.Enabled {
+			enabled = " (disabled)"
+		}
+
+		fmt.Printf("%-*s  %-7s  %s%s\n", longest, item.mod.Name, item.status, item.detail, enabled)
+	}
+
+	fmt.Printf("\n%d ready, %d blocked, %d missing\n", counts[PLAN_READY], counts[PLAN_BLOCKED], counts[PLAN_MISSING])
+
+	return counts
+}
+
+// download the releases of the plan, replacing the installed archives
+func applyUpgrade(dir string, plan []*planItem, noSnapshot bool, keep int) error {
+	var downloads []*api.Release
+	var replaced []string
+
+	for _, item := range plan {
+		archive := item.mod.Archive
+
+		if archive != nil && archive.Version == item.release.Version {
+			continue
+		}
+
+		downloads = append(downloads, item.release)
+
+		if archive != nil {
+			replaced = append(replaced, archive.File)
+		}
+	}
+
+	if len(downloads) == 0 {
+		fmt.Println("Every mod is already on the target version")
+
+		return nil
+	}
+
+	return downloadReleases(dir, downloads, replaced, nil, snapshotBefore(dir, noSnapshot, keep))
+}
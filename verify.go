@@ -0,0 +1,276 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacksfk/modtorio/api"
+	"github.com/blacksfk/modtorio/common"
+	"github.com/blacksfk/modtorio/modinfo"
+	"github.com/blacksfk/modtorio/modlist"
+)
+
+const (
+	V_FLAG_OFFLINE = "offline"
+)
+
+// problems found with an archive, or a mod list entry without one
+type verifyResult struct {
+	file    string // empty if the mod has no archive
+	mod     string
+	version string
+	issues  []string
+}
+
+// a verify result for output
+type verifyEntry struct {
+	File    *string  `json:"file"` // null if the mod has no archive
+	Mod     string   `json:"mod"`
+	Version string   `json:"version"`
+	Issues  []string `json:"issues"`
+}
+
+// check every archive in the mods directory for problems that stop the game
+// from loading it. exits with an error if there are any
+func verify(flags *ModtorioFlags, options []string) error {
+	var offline bool
+
+	verifyFlags := flag.NewFlagSet("Verify flags", flag.ContinueOnError)
+
+	verifyFlags.BoolVar(&offline, V_FLAG_OFFLINE, false, "Do not compare archives against the checksums on the mod portal")
+	e := verifyFlags.Parse(options)
+
+	if e != nil {
+		return e
+	}
+
+	list, e := modlist.Read(flags.dir, flags.factorio)
+
+	if e != nil {
+		return e
+	}
+
+	results, e := verifyArchives(flags.dir, list, !offline)
+
+	if e != nil {
+		return e
+	}
+
+	// archives, archives with problems and mods without an archive
+	archives, failed, missing := 0, 0, 0
+	entries := []*verifyEntry{}
+
+	for _, result := range results {
+		entry := &verifyEntry{Mod: result.mod, Version: result.version, Issues: nonNil(result.issues)}
+
+		if result.file != "" {
+			entry.File = &result.file
+		}
+
+		entries = append(entries, entry)
+	}
+
+	emit(entries)
+
+	for _, result := range results {
+		name := result.file
+
+		if name == "" {
+			name = result.mod
+			missing++
+		} else {
+			archives++
+
+			if len(result.issues) > 0 {
+				failed++
+			}
+		}
+
+		if len(result.issues) == 0 {
+			fmt.Printf("%s: ok\n", name)
+			continue
+		}
+
+		for _, issue := range result.issues {
+			fmt.Printf("%s: %s\n", name, issue)
+		}
+	}
+
+	fmt.Println()
+
+	if missing > 0 {
+		return fmt.Errorf("%d of %d archives have problems, %d mods have no archive", failed, archives, missing)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d archives have problems", failed, archives)
+	}
+
+	fmt.Printf("All %d archives ok\n", archives)
+
+	return nil
+}
+
+// check the structure of every archive in the directory, that every mod in
+// the list has an archive and optionally the archives' portal checksums
+func verifyArchives(dir string, list *modlist.ModList, portal bool) ([]*verifyResult, error) {
+	archives, unparseable, e := modlist.ScanArchives(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	e = list.FindArchives(dir)
+
+	if e != nil {
+		return nil, e
+	}
+
+	var results []*verifyResult
+
+	for _, name := range unparseable {
+		results = append(results, &verifyResult{file: name, issues: []string{"not named <mod>_<version>.zip"}})
+	}
+
+	for _, archive := range archives {
+		result := &verifyResult{file: archive.File, mod: archive.Mod, version: archive.Version}
+		result.issues = checkArchive(filepath.Join(dir, archive.File), archive)
+
+		if list.Get(archive.Mod) == nil {
+			result.issues = append(result.issues, "not in "+modlist.FILE_NAME)
+		}
+
+		results = append(results, result)
+	}
+
+	for _, mod := range list.Mods {
+		if mod.Archive == nil && mod.Name != modlist.BASE {
+			results = append(results, &verifyResult{mod: mod.Name, issues: []string{"in " + modlist.FILE_NAME + " but has no archive"}})
+		}
+	}
+
+	if portal {
+		e = verifyChecksums(dir, list, results)
+
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	return results, nil
+}
+
+// check that an archive is an intact zip file containing the mod its name says
+func checkArchive(path string, archive *modlist.Archive) []string {
+	z, e := zip.OpenReader(path)
+
+	if e != nil {
+		return []string{fmt.Sprintf("not a zip file (%v)", e)}
+	}
+
+	defer z.Close()
+
+	var issues []string
+	roots := map[string]bool{}
+
+	for _, file := range z.File {
+		roots[strings.SplitN(file.Name, "/", 2)[0]] = true
+
+		// the CRC is checked once a file has been read completely
+		if e := checkZipFile(file); e != nil {
+			issues = append(issues, fmt.Sprintf("%s is corrupt (%v)", file.Name, e))
+		}
+	}
+
+	// the game also accepts a top level folder without the version
+	if len(roots) != 1 || !roots[archive.Name] && !roots[archive.Mod] {
+		var found []string
+
+		for root := range roots {
+			found = append(found, root)
+		}
+
+		issues = append(issues, fmt.Sprintf("top level folder should be %s, found: %s", archive.Name, strings.Join(found, " ")))
+	}
+
+	info, e := modinfo.Read(&z.Reader)
+
+	if e != nil {
+		return append(issues, e.Error())
+	}
+
+	if info.Name != archive.Mod {
+		issues = append(issues, fmt.Sprintf("%s name is %s", modinfo.INFO_FILE, info.Name))
+	}
+
+	if info.Version != archive.Version {
+		issues = append(issues, fmt.Sprintf("%s version is %s", modinfo.INFO_FILE, info.Version))
+	}
+
+	return issues
+}
+
+// read a file within a zip to check its CRC
+func checkZipFile(file *zip.File) error {
+	r, e := file.Open()
+
+	if e != nil {
+		return e
+	}
+
+	defer r.Close()
+	_, e = io.Copy(io.Discard, r)
+
+	return e
+}
+
+// compare the archives of mods in the list against the checksums of their
+// releases on the mod portal
+func verifyChecksums(dir string, list *modlist.ModList, results []*verifyResult) error {
+	var names []string
+
+	for _, result := range results {
+		if result.file != "" && list.Get(result.mod) != nil && !modlist.IsDLC(result.mod) {
+			names = append(names, result.mod)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	portal, e := api.GetAll(names...)
+
+	if e != nil {
+		return e
+	}
+
+	for _, result := range results {
+		if result.file == "" {
+			continue
+		}
+
+		release := findRelease(portal, result.mod, result.version)
+
+		if release == nil || release.Sha1 == "" {
+			// private mods and removed releases cannot be checked
+			continue
+		}
+
+		sum, e := common.Sha1File(filepath.Join(dir, result.file))
+
+		if e != nil {
+			return e
+		}
+
+		if sum != release.Sha1 {
+			result.issues = append(result.issues, "does not match the mod portal checksum")
+		}
+	}
+
+	return nil
+}